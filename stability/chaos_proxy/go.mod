@@ -0,0 +1,7 @@
+module stability/chaos_proxy
+
+go 1.21
+
+require stability/faultinjection v0.0.0
+
+replace stability/faultinjection => ../faultinjection
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+const RulesPath = "/_chaos/rules"
+
+func main() {
+	listen := flag.String("listen", ":8090", "address to listen on")
+	upstream := flag.String("upstream", "http://localhost:8085", "upstream base URL")
+	rulesFile := flag.String("rules", "", "JSON file with fault rules (see rules.example.json)")
+	seed := flag.Int64("seed", 0, "random seed, 0 picks one from the clock")
+	flag.Parse()
+
+	target, err := url.Parse(*upstream)
+	if err != nil {
+		log.Fatalf("Invalid upstream: %v", err)
+	}
+
+	var initial []Rule
+	if *rulesFile != "" {
+		initial, err = LoadRules(*rulesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	rules, err := NewRuleSet(initial, *seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(RulesPath, RulesHandler(rules))
+	mux.Handle("/", NewChaosProxy(target, rules))
+
+	fmt.Println("Chaos Proxy")
+	fmt.Println("===========")
+	fmt.Printf("Proxying http://localhost%s -> %s with %d rules\n", *listen, target, len(initial))
+	fmt.Printf("Rules: curl http://localhost%s%s\n", *listen, RulesPath)
+
+	log.Fatal(http.ListenAndServe(*listen, mux))
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const FaultHeader = "X-Chaos-Fault"
+
+type ChaosProxy struct {
+	rules *RuleSet
+	proxy *httputil.ReverseProxy
+}
+
+func NewChaosProxy(upstream *url.URL, rules *RuleSet) *ChaosProxy {
+	p := &ChaosProxy{rules: rules}
+
+	p.proxy = httputil.NewSingleHostReverseProxy(upstream)
+	// Flush every write so slow bodies actually reach the client slowly.
+	p.proxy.FlushInterval = -1
+	p.proxy.ModifyResponse = p.modifyResponse
+
+	return p
+}
+
+type decisionKey struct{}
+
+func (p *ChaosProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d := p.rules.Decide(r)
+
+	if len(d.Applied) > 0 {
+		log.Printf("%s %s: %s", r.Method, r.URL.Path, strings.Join(d.Applied, ", "))
+		w.Header().Set(FaultHeader, strings.Join(d.Applied, ","))
+	}
+
+	if d.Latency > 0 {
+		timer := time.NewTimer(d.Latency)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+
+	if d.Rule != nil {
+		switch d.Rule.Fault {
+		case FaultError:
+			status := d.Rule.Status
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": "injected by chaos proxy"})
+			return
+
+		case FaultReset:
+			resetConnection(w)
+			return
+		}
+	}
+
+	p.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), decisionKey{}, d)))
+}
+
+// modifyResponse applies body faults to the upstream response. Truncated
+// bodies keep the original Content-Length, so clients see an unexpected EOF
+// when the proxy aborts the connection.
+func (p *ChaosProxy) modifyResponse(resp *http.Response) error {
+	d, ok := resp.Request.Context().Value(decisionKey{}).(Decision)
+	if !ok || d.Rule == nil {
+		return nil
+	}
+
+	switch d.Rule.Fault {
+	case FaultTruncate:
+		limit := d.Rule.TruncateAt
+		if limit <= 0 {
+			limit = resp.ContentLength / 2
+		}
+		if limit <= 0 {
+			// Chunked or tiny body: cut after a fixed prefix instead.
+			limit = defaultTruncateAt
+		}
+		resp.Body = &truncatedBody{ReadCloser: resp.Body, remaining: limit}
+
+	case FaultSlowBody:
+		resp.Body = &slowBody{ReadCloser: resp.Body, bytesPerSecond: d.Rule.BytesPerSecond}
+	}
+
+	return nil
+}
+
+var errTruncated = errors.New("body truncated by chaos proxy")
+
+// defaultTruncateAt is used when truncate_at is unset and half the body
+// length is unknown or zero.
+const defaultTruncateAt = 512
+
+type truncatedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, errTruncated
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if err == io.EOF {
+		// The upstream body was shorter than the cut-off; still abort.
+		err = errTruncated
+	}
+	return n, err
+}
+
+// slowBody hands out at most a tenth of bytesPerSecond every 100ms.
+type slowBody struct {
+	io.ReadCloser
+	bytesPerSecond int
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	chunk := b.bytesPerSecond / 10
+	if chunk < 1 {
+		chunk = 1
+	}
+	if len(p) > chunk {
+		p = p[:chunk]
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	return b.ReadCloser.Read(p)
+}
+
+// resetConnection closes the client connection with SO_LINGER=0 so the
+// client gets a TCP RST instead of a clean close.
+func resetConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// RulesHandler serves GET (list) and PUT (replace) for the active rules.
+func RulesHandler(rules *RuleSet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, rules.Rules())
+
+		case http.MethodPut, http.MethodPost:
+			var next []Rule
+			if err := json.NewDecoder(r.Body).Decode(&next); err != nil {
+				http.Error(w, "invalid rules: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := rules.Replace(next); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, http.StatusOK, rules.Rules())
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"stability/faultinjection"
+)
+
+type FaultType string
+
+const (
+	FaultLatency  FaultType = "latency"
+	FaultError    FaultType = "error"
+	FaultReset    FaultType = "reset"
+	FaultTruncate FaultType = "truncate"
+	FaultSlowBody FaultType = "slow_body"
+)
+
+// Rule fires Fault with the given Probability on requests matching Method
+// and PathPrefix (empty matches everything).
+type Rule struct {
+	Name        string    `json:"name"`
+	Method      string    `json:"method,omitempty"`
+	PathPrefix  string    `json:"path_prefix,omitempty"`
+	Probability float64   `json:"probability"`
+	Fault       FaultType `json:"fault"`
+
+	Latency        faultinjection.Duration `json:"latency,omitempty"`
+	Status         int                     `json:"status,omitempty"`
+	TruncateAt     int64                   `json:"truncate_at,omitempty"`
+	BytesPerSecond int                     `json:"bytes_per_second,omitempty"`
+}
+
+func (r Rule) Matches(req *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+	return strings.HasPrefix(req.URL.Path, r.PathPrefix)
+}
+
+func (r Rule) Validate() error {
+	if r.Probability < 0 || r.Probability > 1 {
+		return fmt.Errorf("rule %q: probability must be in [0, 1]", r.Name)
+	}
+
+	switch r.Fault {
+	case FaultLatency:
+		if r.Latency <= 0 {
+			return fmt.Errorf("rule %q: latency fault needs latency", r.Name)
+		}
+	case FaultError:
+		if r.Status != 0 && (r.Status < 500 || r.Status > 599) {
+			return fmt.Errorf("rule %q: error status must be 5xx", r.Name)
+		}
+	case FaultSlowBody:
+		if r.BytesPerSecond <= 0 {
+			return fmt.Errorf("rule %q: slow_body fault needs bytes_per_second", r.Name)
+		}
+	case FaultReset, FaultTruncate:
+	default:
+		return fmt.Errorf("rule %q: unknown fault %q", r.Name, r.Fault)
+	}
+
+	return nil
+}
+
+// Decision is the set of faults picked for one request. Latency rules add
+// up; of the remaining faults the first rule that fires wins.
+type Decision struct {
+	Latency time.Duration
+	Rule    *Rule
+	Applied []string
+}
+
+type RuleSet struct {
+	rules []Rule
+	rand  *rand.Rand
+	mu    sync.Mutex
+}
+
+func NewRuleSet(rules []Rule, seed int64) (*RuleSet, error) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	rs := &RuleSet{rand: rand.New(rand.NewSource(seed))}
+	if err := rs.Replace(rules); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (rs *RuleSet) Rules() []Rule {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return append([]Rule(nil), rs.rules...)
+}
+
+func (rs *RuleSet) Replace(rules []Rule) error {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.rules = append([]Rule(nil), rules...)
+	return nil
+}
+
+func (rs *RuleSet) Decide(req *http.Request) Decision {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	var d Decision
+	for i := range rs.rules {
+		rule := rs.rules[i]
+		if !rule.Matches(req) || rs.rand.Float64() >= rule.Probability {
+			continue
+		}
+
+		if rule.Fault == FaultLatency {
+			d.Latency += time.Duration(rule.Latency)
+			d.Applied = append(d.Applied, rule.Name)
+			continue
+		}
+
+		if d.Rule == nil {
+			d.Rule = &rule
+			d.Applied = append(d.Applied, rule.Name)
+		}
+	}
+
+	return d
+}
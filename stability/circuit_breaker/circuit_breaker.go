@@ -13,6 +13,17 @@ const (
 	StateOpen
 )
 
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
 var ErrCircuitOpen = errors.New("circuit breaker is open")
 
 type CircuitBreaker struct {
@@ -21,6 +32,7 @@ type CircuitBreaker struct {
 	state        State
 	failures     int
 	lastFailTime time.Time
+	onChange     func(from, to State)
 	mu           sync.Mutex
 }
 
@@ -32,12 +44,37 @@ func NewCircuitBreaker(maxFailures int, timeout time.Duration) *CircuitBreaker {
 	}
 }
 
+// OnStateChange registers fn to be called on every state transition. fn runs
+// with the breaker locked and must not call back into it.
+func (cb *CircuitBreaker) OnStateChange(fn func(from, to State)) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.onChange = fn
+}
+
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+func (cb *CircuitBreaker) setState(to State) {
+	from := cb.state
+	cb.state = to
+
+	if from != to && cb.onChange != nil {
+		cb.onChange(from, to)
+	}
+}
+
 func (cb *CircuitBreaker) Call(fn func() error) error {
 	cb.mu.Lock()
 
 	if cb.state == StateOpen {
 		if time.Since(cb.lastFailTime) > cb.timeout {
-			cb.state = StateClosed
+			cb.setState(StateClosed)
 			cb.failures = 0
 		} else {
 			cb.mu.Unlock()
@@ -57,11 +94,11 @@ func (cb *CircuitBreaker) Call(fn func() error) error {
 		cb.lastFailTime = time.Now()
 
 		if cb.failures >= cb.maxFailures {
-			cb.state = StateOpen
+			cb.setState(StateOpen)
 		}
 	} else {
 		cb.failures = 0
-		cb.state = StateClosed
+		cb.setState(StateClosed)
 	}
 
 	return err
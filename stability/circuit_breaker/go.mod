@@ -2,3 +2,11 @@ module circuit_breaker
 
 go 1.21
 
+require (
+	stability/faultinjection v0.0.0
+	stability/metrics v0.0.0
+)
+
+replace stability/metrics => ../metrics
+
+replace stability/faultinjection => ../faultinjection
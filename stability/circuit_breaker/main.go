@@ -1,32 +1,65 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
 	"time"
+
+	"stability/faultinjection"
+	"stability/metrics"
 )
 
-var failCount = 0
+// callTimeout bounds each call so injected hangs fail instead of blocking.
+const callTimeout = 2 * time.Second
+
+func unreliableService(faults *faultinjection.Injector) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
 
-func unreliableService() error {
-	failCount++
-	if failCount%3 == 0 {
-		return nil
+	if err := faults.Inject(ctx); err != nil {
+		return fmt.Errorf("service failed: %w", err)
 	}
-	return errors.New("service failed")
+	return nil
 }
 
 func main() {
 	fmt.Println("Circuit Breaker Demo")
 
+	faults := faultinjection.NewRegistry()
+	serviceFaults := faults.Register(faultinjection.New("unreliable-service", faultinjection.Config{
+		ErrorRate: 0.3,
+		// Down for the first 4 seconds of every 20, long enough to trip the breaker.
+		Outage: faultinjection.OutageConfig{
+			Period:   faultinjection.Duration(20 * time.Second),
+			Duration: faultinjection.Duration(4 * time.Second),
+		},
+		Seed: 42,
+	}))
+
 	cb := NewCircuitBreaker(3, 5*time.Second)
 
+	registry := metrics.NewRegistry()
+	breakerMetrics(registry, "unreliable-service", cb)
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle(faultinjection.AdminPath, faults.AdminHandler(faultinjection.AdminPath))
+		mux.Handle("/metrics", registry.Handler())
+		if err := http.ListenAndServe(":9093", mux); err != nil {
+			log.Printf("Admin server stopped: %v", err)
+		}
+	}()
+	fmt.Printf("Fault admin: http://localhost:9093%s\n", faultinjection.AdminPath)
+	fmt.Println("Metrics: curl http://localhost:9093/metrics")
+
 	for i := 1; i <= 10; i++ {
 		fmt.Printf("Request %d: ", i)
 
 		err := cb.Call(func() error {
-			return unreliableService()
+			return unreliableService(serviceFaults)
 		})
 
 		if err == ErrCircuitOpen {
@@ -39,4 +72,6 @@ func main() {
 
 		time.Sleep(1 * time.Second)
 	}
+	fmt.Println()
+	registry.WriteText(os.Stdout)
 }
@@ -0,0 +1,18 @@
+package main
+
+import "stability/metrics"
+
+// breakerMetrics exports the breaker state (0 closed, 1 open) and counts
+// transitions between states.
+func breakerMetrics(registry *metrics.Registry, name string, cb *CircuitBreaker) {
+	state := registry.NewGauge("circuit_breaker_state",
+		"Current circuit breaker state: 0 closed, 1 open.", "breaker")
+	transitions := registry.NewCounter("circuit_breaker_transitions_total",
+		"Circuit breaker state transitions.", "breaker", "from", "to")
+
+	state.Set(float64(cb.State()), name)
+	cb.OnStateChange(func(from, to State) {
+		state.Set(float64(to), name)
+		transitions.Inc(name, from.String(), to.String())
+	})
+}
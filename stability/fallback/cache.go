@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type cacheEntry[T any] struct {
+	value    T
+	storedAt time.Time
+}
+
+// SWRCache follows Cache-Control semantics: entries younger than ttl are
+// fresh, up to ttl+staleWhileRevalidate they are served while one background
+// refresh per key runs, and up to ttl+staleIfError they back a failed call.
+type SWRCache[T any] struct {
+	ttl                  time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	refreshTimeout       time.Duration
+
+	entries    map[string]cacheEntry[T]
+	refreshing map[string]bool
+	mu         sync.Mutex
+}
+
+func NewSWRCache[T any](ttl, staleWhileRevalidate, staleIfError time.Duration) *SWRCache[T] {
+	return &SWRCache[T]{
+		ttl:                  ttl,
+		staleWhileRevalidate: staleWhileRevalidate,
+		staleIfError:         staleIfError,
+		refreshTimeout:       time.Second,
+		entries:              make(map[string]cacheEntry[T]),
+		refreshing:           make(map[string]bool),
+	}
+}
+
+func (c *SWRCache[T]) Set(key string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cacheEntry[T]{value: value, storedAt: time.Now()}
+}
+
+func (c *SWRCache[T]) lookup(key string, maxAge time.Duration) (T, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		var zero T
+		return zero, 0, false
+	}
+
+	age := time.Since(entry.storedAt)
+	if age > maxAge {
+		var zero T
+		return zero, age, false
+	}
+	return entry.value, age, true
+}
+
+// Through is a primary tier: fresh entries skip fetch, revalidating entries
+// are returned immediately while fetch refreshes them in the background, and
+// otherwise fetch runs inline and successful values are stored. Cached
+// values report their age.
+func (c *SWRCache[T]) Through(key string, fetch func(ctx context.Context) (T, error)) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		if value, age, ok := c.lookup(key, c.ttl+c.staleWhileRevalidate); ok {
+			if age > c.ttl {
+				c.refresh(key, fetch)
+			}
+			ReportAge(ctx, age)
+			return value, nil
+		}
+
+		value, err := fetch(ctx)
+		if err != nil {
+			return value, err
+		}
+
+		c.Set(key, value)
+		return value, nil
+	}
+}
+
+// Stale is an alternative tier serving entries up to ttl+staleIfError old.
+func (c *SWRCache[T]) Stale(key string) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		value, age, ok := c.lookup(key, c.ttl+c.staleIfError)
+		if !ok {
+			return value, ErrNotCached
+		}
+		ReportAge(ctx, age)
+		return value, nil
+	}
+}
+
+func (c *SWRCache[T]) refresh(key string, fetch func(ctx context.Context) (T, error)) {
+	c.mu.Lock()
+	if c.refreshing[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.refreshTimeout)
+		defer cancel()
+
+		value, err := fetch(ctx)
+		if err != nil {
+			log.Printf("  Background refresh of %s failed: %v", key, err)
+			return
+		}
+		c.Set(key, value)
+	}()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+type inflightCall[T any] struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	value   T
+	err     error
+}
+
+type CoalesceStats struct {
+	Requests   int64 `json:"requests"`
+	Executions int64 `json:"executions"`
+}
+
+func (s CoalesceStats) Ratio() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return 1 - float64(s.Executions)/float64(s.Requests)
+}
+
+// Coalescer shares one in-flight call between concurrent callers asking for
+// the same key. The call runs on a context detached from any single caller,
+// keeping the first caller's values but not its cancellation; it is
+// cancelled only when the last waiting caller gives up.
+type Coalescer[T any] struct {
+	calls     map[string]*inflightCall[T]
+	stats     CoalesceStats
+	onRequest func(executed bool)
+	mu        sync.Mutex
+}
+
+func NewCoalescer[T any]() *Coalescer[T] {
+	return &Coalescer[T]{
+		calls: make(map[string]*inflightCall[T]),
+	}
+}
+
+// OnRequest registers fn to be told about every call to Do, and whether it
+// started an execution or joined one already in flight.
+func (c *Coalescer[T]) OnRequest(fn func(executed bool)) *Coalescer[T] {
+	c.onRequest = fn
+	return c
+}
+
+func (c *Coalescer[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	c.stats.Requests++
+
+	call, ok := c.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &inflightCall[T]{done: make(chan struct{}), cancel: cancel}
+		c.calls[key] = call
+		c.stats.Executions++
+
+		go func() {
+			defer cancel()
+			call.value, call.err = fn(callCtx)
+
+			c.mu.Lock()
+			if c.calls[key] == call {
+				delete(c.calls, key)
+			}
+			c.mu.Unlock()
+
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	c.mu.Unlock()
+
+	if c.onRequest != nil {
+		c.onRequest(!ok)
+	}
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		c.leave(key, call)
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// leave cancels the shared call once its last waiter is gone, and forgets
+// it so later callers start a fresh one.
+func (c *Coalescer[T]) leave(key string, call *inflightCall[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+
+	call.cancel()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
+
+func (c *Coalescer[T]) Stats() CoalesceStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+)
+
+type Criticality int
+
+const (
+	Critical Criticality = iota
+	Optional
+)
+
+func (c Criticality) String() string {
+	if c == Optional {
+		return "optional"
+	}
+	return "critical"
+}
+
+type DegradedSet map[string]struct{}
+
+func (d DegradedSet) Add(name string) {
+	d[name] = struct{}{}
+}
+
+func (d DegradedSet) Has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+func (d DegradedSet) List() []string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type dependencyOutcome[T any] struct {
+	result FallbackResult[T]
+	err    error
+}
+
+// PendingDependency is one branch of a fan-out. Critical dependencies only
+// run their primary tier, so critical data is never silently faked.
+type PendingDependency[T any] struct {
+	name        string
+	criticality Criticality
+	chain       *Fallback[T]
+	done        chan dependencyOutcome[T]
+}
+
+func startDependency[T any](ctx context.Context, name string, criticality Criticality, chain *Fallback[T]) *PendingDependency[T] {
+	p := &PendingDependency[T]{
+		name:        name,
+		criticality: criticality,
+		chain:       chain,
+		done:        make(chan dependencyOutcome[T], 1),
+	}
+
+	run := chain
+	if criticality == Critical {
+		run = chain.PrimaryOnly()
+	}
+
+	go func() {
+		result, err := run.Execute(ctx)
+		p.done <- dependencyOutcome[T]{result: result, err: err}
+	}()
+
+	return p
+}
+
+// Await returns the dependency's answer, or once ctx expires falls back to
+// the chain's alternatives and marks the dependency degraded.
+func (p *PendingDependency[T]) Await(ctx context.Context, degraded DegradedSet) (FallbackResult[T], error) {
+	var out dependencyOutcome[T]
+
+	select {
+	case out = <-p.done:
+	case <-ctx.Done():
+		out.err = ctx.Err()
+		log.Printf("  %s: no answer before deadline", p.name)
+	}
+
+	for _, tierErr := range out.result.Errors {
+		log.Printf("  %s tier failed: %v", p.name, tierErr)
+	}
+
+	if out.err != nil {
+		if p.criticality == Critical {
+			return out.result, fmt.Errorf("critical dependency %s failed: %w", p.name, out.err)
+		}
+
+		degraded.Add(p.name)
+
+		if ctx.Err() != nil {
+			// Alternatives are expected to be local (cache, static value),
+			// so they may still answer after the shared deadline.
+			out.result, _ = p.chain.Alternatives().Execute(context.WithoutCancel(ctx))
+			out.result.Degraded = true
+		}
+		return out.result, nil
+	}
+
+	if out.result.Degraded {
+		degraded.Add(p.name)
+	}
+	log.Printf("  %s from tier %q (%s)", p.name, out.result.Tier, p.criticality)
+
+	return out.result, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrAllTiersFailed = errors.New("all fallback tiers failed")
+
+type Tier[T any] struct {
+	Name string
+	Fn   func(ctx context.Context) (T, error)
+}
+
+// FallbackResult describes the tier that answered. Age is how old its value
+// was, as reported by the tier through ReportAge, and zero for live values.
+type FallbackResult[T any] struct {
+	Value    T
+	Tier     string
+	Degraded bool
+	Age      time.Duration
+	Errors   []error
+}
+
+type ageKey struct{}
+
+// ReportAge lets a tier serving a stored value say how old it is. Execute
+// gives every tier call its own slot, so concurrent chains never mix ages.
+func ReportAge(ctx context.Context, age time.Duration) {
+	if slot, ok := ctx.Value(ageKey{}).(*time.Duration); ok {
+		*slot = age
+	}
+}
+
+// Fallback tries the primary tier and then each alternative in order until
+// one succeeds. Tiers are plain functions, so a retry executor, circuit
+// breaker or timeout wrapper can sit inside any of them.
+type Fallback[T any] struct {
+	tiers []Tier[T]
+}
+
+func NewFallback[T any](name string, primary func(ctx context.Context) (T, error)) *Fallback[T] {
+	return &Fallback[T]{
+		tiers: []Tier[T]{{Name: name, Fn: primary}},
+	}
+}
+
+func (f *Fallback[T]) Or(name string, fn func(ctx context.Context) (T, error)) *Fallback[T] {
+	f.tiers = append(f.tiers, Tier[T]{Name: name, Fn: fn})
+	return f
+}
+
+func (f *Fallback[T]) OrValue(name string, value T) *Fallback[T] {
+	return f.Or(name, func(context.Context) (T, error) {
+		return value, nil
+	})
+}
+
+func (f *Fallback[T]) PrimaryOnly() *Fallback[T] {
+	return &Fallback[T]{tiers: f.tiers[:1:1]}
+}
+
+func (f *Fallback[T]) Alternatives() *Fallback[T] {
+	return &Fallback[T]{tiers: append([]Tier[T](nil), f.tiers[1:]...)}
+}
+
+func (f *Fallback[T]) Execute(ctx context.Context) (FallbackResult[T], error) {
+	var errs []error
+
+	for i, tier := range f.tiers {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		var age time.Duration
+		value, err := tier.Fn(context.WithValue(ctx, ageKey{}, &age))
+		if err == nil {
+			return FallbackResult[T]{
+				Value:    value,
+				Tier:     tier.Name,
+				Degraded: i > 0,
+				Age:      age,
+				Errors:   errs,
+			}, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", tier.Name, err))
+	}
+
+	return FallbackResult[T]{Errors: errs}, errors.Join(append([]error{ErrAllTiersFailed}, errs...)...)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type productResponse struct {
+	ProductID       string   `json:"product_id"`
+	Price           float64  `json:"price"`
+	Recommendations []string `json:"recommendations"`
+	Review          string   `json:"review"`
+	ReviewTier      string   `json:"review_tier"`
+	ReviewAgeMs     int64    `json:"review_age_ms,omitempty"`
+	Degraded        []string `json:"degraded"`
+}
+
+type coalescingResponse struct {
+	CoalesceStats
+	Ratio float64 `json:"ratio"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ProductHandler serves GET /products/{id}. Degraded components are listed in
+// X-Degraded and Warning headers; only a critical dependency failure is a 503.
+func ProductHandler(productService *ProductService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+
+		productID := strings.TrimPrefix(r.URL.Path, "/products/")
+		if productID == "" || strings.Contains(productID, "/") {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "product not found"})
+			return
+		}
+
+		result, err := productService.GetProduct(r.Context(), productID)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+			return
+		}
+
+		degraded := result.Degraded.List()
+		if len(degraded) > 0 {
+			joined := strings.Join(degraded, ", ")
+			w.Header().Set("X-Degraded", joined)
+			w.Header().Set("Warning", `199 - "degraded: `+joined+`"`)
+		}
+
+		writeJSON(w, http.StatusOK, productResponse{
+			ProductID:       result.ProductID,
+			Price:           result.Price,
+			Recommendations: result.Recommendations,
+			Review:          result.Review,
+			ReviewTier:      result.ReviewTier,
+			ReviewAgeMs:     result.ReviewAge.Milliseconds(),
+			Degraded:        degraded,
+		})
+	})
+}
+
+func CoalescingStatsHandler(productService *ProductService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := productService.CoalescingStats()
+		response := make(map[string]coalescingResponse, len(stats))
+		for dependency, s := range stats {
+			response[dependency] = coalescingResponse{CoalesceStats: s, Ratio: s.Ratio()}
+		}
+		writeJSON(w, http.StatusOK, response)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -3,37 +3,53 @@ package main
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"time"
+
+	"stability/faultinjection"
+	"stability/metrics"
 )
 
 func main() {
-	recService := NewRecommendationService(0.2)
-	reviewService := NewReviewService(0.7)
-	productService := NewProductService(recService, reviewService)
-
-	for i := 1; i <= 5; i++ {
-		fmt.Printf("Example %d:\n", i)
-
-		result, err := productService.GetProduct("product-1")
-
-		if err != nil {
-			log.Printf("Failed to get product: %v\n", err)
-			fmt.Println(string(make([]byte, 50)))
-			fmt.Println()
-			continue
-		}
-
-		fmt.Printf("Product: %s\n", result.ProductID)
-		fmt.Printf("Price: $%.2f\n", result.Price)
-		fmt.Printf("Recommendations: %v\n", result.Recommendations)
-		fmt.Printf("Review: %s\n", result.Review)
-
-		if result.IsGD {
-			fmt.Printf("Status: isGD=true (review from cache)\n")
-		} else {
-			fmt.Printf("Status: isGD=false (review is live)\n")
-		}
-
-		fmt.Println(string(make([]byte, 50)))
-		fmt.Println()
-	}
+	fmt.Println("Fallback / Graceful Degradation Demo")
+	fmt.Println("====================================")
+	fmt.Println()
+
+	faults := faultinjection.NewRegistry()
+	recFaults := faults.Register(faultinjection.New(DependencyRecommendations, faultinjection.Config{
+		ErrorRate: 0.2,
+		Latency: faultinjection.LatencyConfig{
+			Distribution: faultinjection.DistributionUniform,
+			Max:          faultinjection.Duration(150 * time.Millisecond),
+		},
+	}))
+	reviewFaults := faults.Register(faultinjection.New(DependencyReviews, faultinjection.Config{
+		ErrorRate: 0.7,
+		Latency: faultinjection.LatencyConfig{
+			Distribution: faultinjection.DistributionUniform,
+			Max:          faultinjection.Duration(300 * time.Millisecond),
+		},
+	}))
+
+	recService := NewRecommendationService(recFaults)
+	reviewService := NewReviewService(reviewFaults)
+	productService := NewProductService(recService, reviewService, 200*time.Millisecond)
+
+	registry := metrics.NewRegistry()
+	fallbackMetrics(registry, productService)
+
+	mux := http.NewServeMux()
+	mux.Handle("/products/", ProductHandler(productService))
+	mux.Handle("/debug/coalescing", CoalescingStatsHandler(productService))
+	mux.Handle(faultinjection.AdminPath, faults.AdminHandler(faultinjection.AdminPath))
+	mux.Handle("/metrics", registry.Handler())
+
+	port := ":8085"
+	fmt.Printf("Server starting on http://localhost%s\n", port)
+	fmt.Println("Recommendations and reviews are optional and degrade to fallback data")
+	fmt.Printf("Try: curl -i http://localhost%s/products/product-1\n", port)
+	fmt.Printf("Faults: curl http://localhost%s%s\n", port, faultinjection.AdminPath)
+	fmt.Printf("Metrics: curl http://localhost%s/metrics\n", port)
+
+	log.Fatal(http.ListenAndServe(port, mux))
 }
@@ -0,0 +1,24 @@
+package main
+
+import "stability/metrics"
+
+func fallbackMetrics(registry *metrics.Registry, productService *ProductService) {
+	tiers := registry.NewCounter("fallback_tier_total",
+		"Dependency results by the fallback tier that produced them.", "dependency", "tier")
+
+	productService.OnTier(func(dependency, tier string) {
+		tiers.Inc(dependency, tier)
+	})
+
+	requests := registry.NewCounter("fallback_coalesce_requests_total",
+		"Dependency calls made through the coalescer.", "dependency")
+	executions := registry.NewCounter("fallback_coalesce_executions_total",
+		"Dependency calls that started an execution instead of sharing one in flight.", "dependency")
+
+	productService.OnCoalesce(func(dependency string, executed bool) {
+		requests.Inc(dependency)
+		if executed {
+			executions.Inc(dependency)
+		}
+	})
+}
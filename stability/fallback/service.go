@@ -1,60 +1,113 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
-	"math/rand"
+	"time"
+
+	"stability/faultinjection"
 )
 
 type RecommendationService struct {
-	failureRate float32
+	faults *faultinjection.Injector
 }
 
-func NewRecommendationService(failureRate float32) *RecommendationService {
+func NewRecommendationService(faults *faultinjection.Injector) *RecommendationService {
 	return &RecommendationService{
-		failureRate: failureRate,
+		faults: faults,
 	}
 }
 
-func (r *RecommendationService) GetRecommendations(productID string) ([]string, error) {
-	if rand.Float32() < r.failureRate {
-		return nil, errors.New("recommendation service unavailable")
+func (r *RecommendationService) GetRecommendations(ctx context.Context, productID string) ([]string, error) {
+	if err := r.faults.Inject(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("recommendation service unavailable: %w", err)
 	}
 	return []string{"Product A", "Product B", "Product C"}, nil
 }
 
 type ReviewService struct {
-	failureRate float32
+	faults *faultinjection.Injector
 }
 
-func NewReviewService(failureRate float32) *ReviewService {
+func NewReviewService(faults *faultinjection.Injector) *ReviewService {
 	return &ReviewService{
-		failureRate: failureRate,
+		faults: faults,
 	}
 }
 
-func (r *ReviewService) GetReviews(productID string) (string, error) {
-	if rand.Float32() < r.failureRate {
-		return "", errors.New("review service unavailable")
+func (r *ReviewService) GetReviews(ctx context.Context, productID string) (string, error) {
+	if err := r.faults.Inject(ctx); err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("review service unavailable: %w", err)
 	}
 	return "Excellent product!", nil
 }
 
+var ErrNotCached = errors.New("no cached value")
+
+const (
+	DependencyRecommendations = "recommendations"
+	DependencyReviews         = "reviews"
+)
+
+var popularProducts = []string{"Bestseller 1", "Bestseller 2", "Bestseller 3"}
+
 type ProductService struct {
 	recommendationService *RecommendationService
 	reviewService         *ReviewService
-	cachedReviews         map[string]string
+	reviewCache           *SWRCache[string]
+	recommendationCalls   *Coalescer[[]string]
+	reviewCalls           *Coalescer[string]
+	criticality           map[string]Criticality
+	budget                time.Duration
+	onTier                func(dependency, tier string)
 }
 
-func NewProductService(recService *RecommendationService, revService *ReviewService) *ProductService {
+func NewProductService(recService *RecommendationService, revService *ReviewService, budget time.Duration) *ProductService {
 	return &ProductService{
 		recommendationService: recService,
 		reviewService:         revService,
-		cachedReviews: map[string]string{
-			"product-1": "Good (cached)",
-			"product-2": "Very good (cached)",
-			"product-3": "Excellent (cached)",
+		reviewCache:           NewSWRCache[string](1*time.Second, 1*time.Second, 5*time.Minute),
+		recommendationCalls:   NewCoalescer[[]string](),
+		reviewCalls:           NewCoalescer[string](),
+		criticality: map[string]Criticality{
+			DependencyRecommendations: Optional,
+			DependencyReviews:         Optional,
 		},
+		budget: budget,
+	}
+}
+
+func (s *ProductService) SetCriticality(dependency string, criticality Criticality) *ProductService {
+	s.criticality[dependency] = criticality
+	return s
+}
+
+// OnTier registers fn to be told which fallback tier answered for each
+// dependency, or "failed" when a critical dependency had no answer.
+func (s *ProductService) OnTier(fn func(dependency, tier string)) *ProductService {
+	s.onTier = fn
+	return s
+}
+
+// OnCoalesce registers fn to be told, for each dependency call, whether it
+// started an execution or shared one already in flight.
+func (s *ProductService) OnCoalesce(fn func(dependency string, executed bool)) *ProductService {
+	s.recommendationCalls.OnRequest(func(executed bool) { fn(DependencyRecommendations, executed) })
+	s.reviewCalls.OnRequest(func(executed bool) { fn(DependencyReviews, executed) })
+	return s
+}
+
+func (s *ProductService) observeTier(dependency, tier string) {
+	if s.onTier != nil {
+		s.onTier(dependency, tier)
 	}
 }
 
@@ -63,47 +116,70 @@ type ProductResult struct {
 	Price           float64
 	Recommendations []string
 	Review          string
-	IsGD            bool
+	ReviewTier      string
+	ReviewAge       time.Duration
+	Degraded        DegradedSet
 }
 
-func (s *ProductService) GetProduct(productID string) (ProductResult, error) {
-	log.Printf("Getting product: %s", productID)
+func (s *ProductService) GetProduct(ctx context.Context, productID string) (ProductResult, error) {
+	log.Printf("Getting product: %s (budget: %v)", productID, s.budget)
 
-	price := 99.99
+	ctx, cancel := context.WithTimeout(ctx, s.budget)
+	defer cancel()
 
-	log.Printf("  Getting recommendations...")
-	recommendations, err := s.recommendationService.GetRecommendations(productID)
+	price := 99.99
+	degraded := make(DegradedSet)
+
+	recommendations := NewFallback("live", func(ctx context.Context) ([]string, error) {
+		return s.recommendationCalls.Do(ctx, productID, func(ctx context.Context) ([]string, error) {
+			return s.recommendationService.GetRecommendations(ctx, productID)
+		})
+	}).
+		OrValue("popular", popularProducts)
+
+	reviews := NewFallback("primary", s.reviewCache.Through(productID, func(ctx context.Context) (string, error) {
+		return s.reviewCalls.Do(ctx, productID, func(ctx context.Context) (string, error) {
+			return s.reviewService.GetReviews(ctx, productID)
+		})
+	})).
+		Or("stale", s.reviewCache.Stale(productID)).
+		OrValue("default", "No reviews available")
+
+	pendingRecs := startDependency(ctx, DependencyRecommendations, s.criticality[DependencyRecommendations], recommendations)
+	pendingReviews := startDependency(ctx, DependencyReviews, s.criticality[DependencyReviews], reviews)
+
+	recResult, err := pendingRecs.Await(ctx, degraded)
 	if err != nil {
-		log.Printf("  Recommendation service failed: %v", err)
+		s.observeTier(DependencyRecommendations, "failed")
+		log.Printf("  %v", err)
 		return ProductResult{}, err
 	}
-	log.Printf("  Recommendations OK")
-
-	log.Printf("  Getting reviews...")
-	review, err := s.reviewService.GetReviews(productID)
-	isGD := false
+	s.observeTier(DependencyRecommendations, recResult.Tier)
 
+	reviewResult, err := pendingReviews.Await(ctx, degraded)
 	if err != nil {
-		log.Printf("  Review service failed: %v", err)
-		log.Printf("  Using cached review (fallback, isGD=true)")
-
-		if cachedReview, ok := s.cachedReviews[productID]; ok {
-			review = cachedReview
-		} else {
-			review = "No reviews available"
-		}
-		isGD = true
-	} else {
-		log.Printf("  Review OK (isGD=false)")
+		s.observeTier(DependencyReviews, "failed")
+		log.Printf("  %v", err)
+		return ProductResult{}, err
 	}
+	s.observeTier(DependencyReviews, reviewResult.Tier)
 
 	log.Println()
 
 	return ProductResult{
 		ProductID:       productID,
 		Price:           price,
-		Recommendations: recommendations,
-		Review:          review,
-		IsGD:            isGD,
+		Recommendations: recResult.Value,
+		Review:          reviewResult.Value,
+		ReviewTier:      reviewResult.Tier,
+		ReviewAge:       reviewResult.Age,
+		Degraded:        degraded,
 	}, nil
 }
+
+func (s *ProductService) CoalescingStats() map[string]CoalesceStats {
+	return map[string]CoalesceStats{
+		DependencyRecommendations: s.recommendationCalls.Stats(),
+		DependencyReviews:         s.reviewCalls.Stats(),
+	}
+}
@@ -0,0 +1,97 @@
+package faultinjection
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const AdminPath = "/admin/faults/"
+
+type Registry struct {
+	injectors map[string]*Injector
+	mu        sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		injectors: make(map[string]*Injector),
+	}
+}
+
+func (r *Registry) Register(injector *Injector) *Injector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.injectors[injector.Name()] = injector
+	return injector
+}
+
+func (r *Registry) Get(name string) (*Injector, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	injector, ok := r.injectors[name]
+	return injector, ok
+}
+
+// AdminHandler exposes the registry under prefix:
+//
+//	GET  {prefix}         all injector configs
+//	GET  {prefix}{name}   one injector config
+//	PUT  {prefix}{name}   replace one injector config (JSON body)
+func (r *Registry) AdminHandler(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(req.URL.Path, prefix)
+
+		if name == "" {
+			if req.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			writeJSON(w, http.StatusOK, r.configs())
+			return
+		}
+
+		injector, ok := r.Get(name)
+		if !ok {
+			http.Error(w, "unknown injector: "+name, http.StatusNotFound)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, injector.Config())
+
+		case http.MethodPut, http.MethodPost:
+			var config Config
+			if err := json.NewDecoder(req.Body).Decode(&config); err != nil {
+				http.Error(w, "invalid config: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			injector.SetConfig(config)
+			writeJSON(w, http.StatusOK, injector.Config())
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (r *Registry) configs() map[string]Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	configs := make(map[string]Config, len(r.injectors))
+	for name, injector := range r.injectors {
+		configs[name] = injector.Config()
+	}
+	return configs
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,63 @@
+package faultinjection
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Duration is a time.Duration that reads and writes JSON as "150ms".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+type Distribution string
+
+const (
+	DistributionFixed       Distribution = "fixed"
+	DistributionUniform     Distribution = "uniform"
+	DistributionNormal      Distribution = "normal"
+	DistributionExponential Distribution = "exponential"
+)
+
+// LatencyConfig describes the delay added before every call. Fixed uses
+// Mean, Uniform draws from [Min, Max], Normal uses Mean and StdDev and
+// Exponential uses Mean; the result is always clamped to [Min, Max].
+type LatencyConfig struct {
+	Distribution Distribution `json:"distribution,omitempty"`
+	Mean         Duration     `json:"mean,omitempty"`
+	StdDev       Duration     `json:"stddev,omitempty"`
+	Min          Duration     `json:"min,omitempty"`
+	Max          Duration     `json:"max,omitempty"`
+}
+
+// OutageConfig takes the dependency down for Duration at the start of every
+// Period, counted from the moment the injector was created.
+type OutageConfig struct {
+	Period   Duration `json:"period,omitempty"`
+	Duration Duration `json:"duration,omitempty"`
+}
+
+type Config struct {
+	ErrorRate float64       `json:"error_rate"`
+	HangRate  float64       `json:"hang_rate"`
+	Latency   LatencyConfig `json:"latency"`
+	Outage    OutageConfig  `json:"outage"`
+	Seed      int64         `json:"seed,omitempty"`
+}
@@ -0,0 +1,3 @@
+module stability/faultinjection
+
+go 1.21
@@ -0,0 +1,144 @@
+package faultinjection
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInjected = errors.New("injected fault")
+	ErrOutage   = errors.New("injected outage")
+)
+
+// Injector adds latency, errors, hangs and scheduled outages in front of a
+// simulated dependency. With a non-zero Seed the sequence of faults is
+// reproducible.
+type Injector struct {
+	name    string
+	config  Config
+	rand    *rand.Rand
+	started time.Time
+	mu      sync.Mutex
+}
+
+func New(name string, config Config) *Injector {
+	return &Injector{
+		name:    name,
+		config:  config,
+		rand:    newRand(config.Seed),
+		started: time.Now(),
+	}
+}
+
+func newRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+func (i *Injector) Name() string {
+	return i.name
+}
+
+func (i *Injector) Config() Config {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.config
+}
+
+func (i *Injector) SetConfig(config Config) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if config.Seed != i.config.Seed {
+		i.rand = newRand(config.Seed)
+	}
+	i.config = config
+}
+
+// Inject is called at the top of a simulated call. It waits out the drawn
+// latency, hangs until ctx ends, or returns an injected error.
+func (i *Injector) Inject(ctx context.Context) error {
+	delay, hang, err := i.draw()
+
+	if hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
+
+func (i *Injector) draw() (time.Duration, bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.inOutage() {
+		return 0, false, ErrOutage
+	}
+
+	if i.config.HangRate > 0 && i.rand.Float64() < i.config.HangRate {
+		return 0, true, nil
+	}
+
+	delay := i.latency()
+
+	if i.config.ErrorRate > 0 && i.rand.Float64() < i.config.ErrorRate {
+		return delay, false, ErrInjected
+	}
+
+	return delay, false, nil
+}
+
+func (i *Injector) inOutage() bool {
+	outage := i.config.Outage
+	if outage.Period <= 0 || outage.Duration <= 0 {
+		return false
+	}
+
+	elapsed := time.Since(i.started) % time.Duration(outage.Period)
+	return elapsed < time.Duration(outage.Duration)
+}
+
+func (i *Injector) latency() time.Duration {
+	l := i.config.Latency
+
+	var delay float64
+	switch l.Distribution {
+	case DistributionFixed:
+		delay = float64(l.Mean)
+	case DistributionUniform:
+		delay = float64(l.Min) + i.rand.Float64()*float64(l.Max-l.Min)
+	case DistributionNormal:
+		delay = float64(l.Mean) + i.rand.NormFloat64()*float64(l.StdDev)
+	case DistributionExponential:
+		delay = i.rand.ExpFloat64() * float64(l.Mean)
+	default:
+		return 0
+	}
+
+	if delay < float64(l.Min) {
+		delay = float64(l.Min)
+	}
+	if l.Max > 0 && delay > float64(l.Max) {
+		delay = float64(l.Max)
+	}
+
+	return time.Duration(delay)
+}
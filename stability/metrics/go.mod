@@ -0,0 +1,3 @@
+module stability/metrics
+
+go 1.21
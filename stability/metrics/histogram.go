@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+type Histogram struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*histogramSeries
+	mu         sync.Mutex
+}
+
+// NewHistogram creates a histogram with the given upper bounds; +Inf is
+// added automatically.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &Histogram{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*histogramSeries),
+	}
+	r.register(name, h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	if len(labelValues) != len(h.labelNames) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", h.name, len(h.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (h *Histogram) writeText(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := writeHeader(w, h.name, h.help, "histogram"); err != nil {
+		return err
+	}
+
+	names := append(append([]string(nil), h.labelNames...), "le")
+
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+
+		for i, bound := range h.buckets {
+			labels := formatLabels(names, append(append([]string(nil), s.labelValues...), formatValue(bound)))
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, s.counts[i]); err != nil {
+				return err
+			}
+		}
+
+		labels := formatLabels(names, append(append([]string(nil), s.labelValues...), formatValue(math.Inf(1))))
+		base := formatLabels(h.labelNames, s.labelValues)
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, labels, s.count,
+			h.name, base, formatValue(s.sum),
+			h.name, base, s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+)
+
+// LimiterMetrics counts rate limiter decisions per algorithm and client IP.
+type LimiterMetrics struct {
+	allowed  *Counter
+	rejected *Counter
+}
+
+func NewLimiterMetrics(registry *Registry) *LimiterMetrics {
+	return &LimiterMetrics{
+		allowed: registry.NewCounter("rate_limiter_allowed_total",
+			"Requests admitted by the rate limiter.", "algorithm", "key"),
+		rejected: registry.NewCounter("rate_limiter_rejected_total",
+			"Requests rejected by the rate limiter.", "algorithm", "key"),
+	}
+}
+
+// Observer returns a hook for the limiter middlewares' DecisionObserver.
+func (m *LimiterMetrics) Observer(algorithm string) func(r *http.Request, allowed bool) {
+	return func(r *http.Request, allowed bool) {
+		if allowed {
+			m.allowed.Inc(algorithm, ClientKey(r))
+		} else {
+			m.rejected.Inc(algorithm, ClientKey(r))
+		}
+	}
+}
+
+// ClientKey is the client IP of r, or RemoteAddr if it has no port.
+func ClientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,234 @@
+// Package metrics is a dependency-free subset of the Prometheus client:
+// labelled counters, gauges and histograms rendered in the text exposition
+// format. The stability demos only expose hooks; wiring them to this package
+// is left to each main, so the primitives themselves never import it.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type collector interface {
+	writeText(w io.Writer) error
+}
+
+type Registry struct {
+	collectors []collector
+	names      map[string]bool
+	mu         sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		if err := c.writeText(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registry at /metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// family holds one value per label combination.
+type family struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	values     map[string]float64
+	labels     map[string][]string
+	mu         sync.Mutex
+}
+
+func newFamily(name, help, kind string, labelNames []string) *family {
+	return &family{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+		labels:     make(map[string][]string),
+	}
+}
+
+func (f *family) key(labelValues []string) string {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (f *family) add(delta float64, labelValues []string) {
+	key := f.key(labelValues)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.values[key] += delta
+	f.labels[key] = append([]string(nil), labelValues...)
+}
+
+func (f *family) set(value float64, labelValues []string) {
+	key := f.key(labelValues)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.values[key] = value
+	f.labels[key] = append([]string(nil), labelValues...)
+}
+
+func (f *family) writeText(w io.Writer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := writeHeader(w, f.name, f.help, f.kind); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(f.values) {
+		labels := formatLabels(f.labelNames, f.labels[key])
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", f.name, labels, formatValue(f.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Counter struct{ f *family }
+
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{f: newFamily(name, help, "counter", labelNames)}
+	r.register(name, c.f)
+	return c
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.f.add(1, labelValues)
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counter " + c.f.name + " cannot decrease")
+	}
+	c.f.add(delta, labelValues)
+}
+
+type Gauge struct{ f *family }
+
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{f: newFamily(name, help, "gauge", labelNames)}
+	r.register(name, g.f)
+	return g
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.f.set(value, labelValues)
+}
+
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.f.add(delta, labelValues)
+}
+
+// funcMetric reads its value at scrape time, for state that is already
+// counted elsewhere.
+type funcMetric struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+func (m *funcMetric) writeText(w io.Writer) error {
+	if err := writeHeader(w, m.name, m.help, m.kind); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", m.name, formatValue(m.fn()))
+	return err
+}
+
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, kind: "counter", fn: fn})
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+func writeHeader(w io.Writer, name, help, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+	return err
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -5,26 +5,34 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"stability/metrics"
 )
 
 func main() {
 	fmt.Println("Fixed Window Rate Limiter Demo")
-	fmt.Println("===============================\n")
+	fmt.Println("===============================")
+	fmt.Println()
 
 	limiter := NewFixedWindow(10, 10*time.Second)
 
+	registry := metrics.NewRegistry()
+
 	mux := http.NewServeMux()
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Request processed successfully"))
 	})
 
-	mux.Handle("/api", RateLimitMiddleware(limiter)(handler))
+	mux.Handle("/api", RateLimitMiddleware(limiter, metrics.NewLimiterMetrics(registry).Observer("fixed_window"))(handler))
+	mux.Handle("/metrics", registry.Handler())
 
 	port := ":8083"
 	fmt.Printf("Server starting on http://localhost%s\n", port)
 	fmt.Println("Limit: 10 requests per 10 seconds")
 	fmt.Printf("Try: curl http://localhost%s/api\n", port)
+	fmt.Printf("Metrics: curl http://localhost%s/metrics\n", port)
+	fmt.Println("Compare all limiters: cd ../loadgen && go run . -shape burst")
 
 	log.Fatal(http.ListenAndServe(port, mux))
 }
@@ -4,26 +4,34 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+
+	"stability/metrics"
 )
 
 func main() {
 	fmt.Println("Leaky Bucket Rate Limiter Demo")
-	fmt.Println("===============================\n")
+	fmt.Println("===============================")
+	fmt.Println()
 
 	limiter := NewLeakyBucket(10, 5)
 
+	registry := metrics.NewRegistry()
+
 	mux := http.NewServeMux()
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Request processed successfully"))
 	})
 
-	mux.Handle("/api", RateLimitMiddleware(limiter)(handler))
+	mux.Handle("/api", RateLimitMiddleware(limiter, metrics.NewLimiterMetrics(registry).Observer("leaky_bucket"))(handler))
+	mux.Handle("/metrics", registry.Handler())
 
 	port := ":8082"
 	fmt.Printf("Server starting on http://localhost%s\n", port)
 	fmt.Println("Limit: 10 requests queue, leak rate: 5 requests/sec")
 	fmt.Printf("Try: curl http://localhost%s/api\n", port)
+	fmt.Printf("Metrics: curl http://localhost%s/metrics\n", port)
+	fmt.Println("Compare all limiters: cd ../loadgen && go run . -shape burst")
 
 	log.Fatal(http.ListenAndServe(port, mux))
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultTargets = "token_bucket=http://localhost:8081/api," +
+	"leaky_bucket=http://localhost:8082/api," +
+	"fixed_window=http://localhost:8083/api," +
+	"sliding_window=http://localhost:8084/api"
+
+func main() {
+	shape := flag.String("shape", "steady", "traffic shape: steady, burst, sawtooth, poisson, boundary")
+	rate := flag.Float64("rate", 5, "average requests per second")
+	duration := flag.Duration("duration", 30*time.Second, "how long to send traffic")
+	period := flag.Duration("period", 10*time.Second, "cycle length for burst, sawtooth and boundary; match the limiter window for boundary")
+	burstSize := flag.Int("burst", 10, "requests per burst for burst and boundary")
+	seed := flag.Int64("seed", 1, "seed for the poisson shape")
+	bucket := flag.Duration("bucket", time.Second, "timeline bucket size")
+	peakWindow := flag.Duration("peak-window", 10*time.Second, "interval used for the peak allowed count")
+	targetsFlag := flag.String("targets", defaultTargets, "comma-separated name=url list")
+	format := flag.String("format", "table", "output format: table, csv, json")
+	out := flag.String("out", "", "write the report to this file instead of stdout")
+	flag.Parse()
+
+	if *bucket <= 0 {
+		log.Fatal("bucket must be positive")
+	}
+	if *peakWindow <= 0 {
+		log.Fatal("peak-window must be positive")
+	}
+
+	targets, err := parseTargets(*targetsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := ShapeConfig{
+		Shape:    *shape,
+		Rate:     *rate,
+		Duration: *duration,
+		Period:   *period,
+		Burst:    *burstSize,
+		Seed:     *seed,
+	}
+
+	schedule, err := Schedule(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Sending %d requests (%s) to %d limiters over %v", len(schedule), cfg.Shape, len(targets), cfg.Duration)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	results := Run(client, targets, schedule)
+	report := BuildReport(cfg, targets, results, *bucket, *peakWindow)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "table":
+		WriteTable(w, report)
+	case "csv":
+		err = WriteCSV(w, report)
+	case "json":
+		err = WriteJSON(w, report)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parseTargets(s string) ([]Target, error) {
+	var targets []Target
+	for _, part := range strings.Split(s, ",") {
+		name, url, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("invalid target %q, want name=url", part)
+		}
+		targets = append(targets, Target{Name: name, URL: url})
+	}
+	return targets, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Bucket struct {
+	StartMs  int64 `json:"start_ms"`
+	Allowed  int   `json:"allowed"`
+	Rejected int   `json:"rejected"`
+	Errors   int   `json:"errors"`
+}
+
+type LimiterReport struct {
+	Limiter  string `json:"limiter"`
+	URL      string `json:"url"`
+	Sent     int    `json:"sent"`
+	Allowed  int    `json:"allowed"`
+	Rejected int    `json:"rejected"`
+	Errors   int    `json:"errors"`
+	// PeakAllowed is the most requests let through in any interval of
+	// PeakWindow. Above the configured limit it exposes the fixed-window
+	// boundary burst.
+	PeakAllowed int      `json:"peak_allowed"`
+	Timeline    []Bucket `json:"timeline"`
+}
+
+type Report struct {
+	Shape        string          `json:"shape"`
+	Rate         float64         `json:"rate"`
+	DurationMs   int64           `json:"duration_ms"`
+	BucketMs     int64           `json:"bucket_ms"`
+	PeakWindowMs int64           `json:"peak_window_ms"`
+	Limiters     []LimiterReport `json:"limiters"`
+}
+
+func BuildReport(cfg ShapeConfig, targets []Target, results map[string][]Sample, bucket, peakWindow time.Duration) Report {
+	report := Report{
+		Shape:        cfg.Shape,
+		Rate:         cfg.Rate,
+		DurationMs:   cfg.Duration.Milliseconds(),
+		BucketMs:     bucket.Milliseconds(),
+		PeakWindowMs: peakWindow.Milliseconds(),
+	}
+
+	for _, target := range targets {
+		samples := results[target.Name]
+		lr := LimiterReport{
+			Limiter:     target.Name,
+			URL:         target.URL,
+			Sent:        len(samples),
+			PeakAllowed: peakAllowed(samples, peakWindow),
+		}
+
+		buckets := make(map[int64]*Bucket)
+		var last int64
+		for _, s := range samples {
+			idx := int64(s.Offset / bucket)
+			if idx > last {
+				last = idx
+			}
+			b, ok := buckets[idx]
+			if !ok {
+				b = &Bucket{StartMs: idx * bucket.Milliseconds()}
+				buckets[idx] = b
+			}
+
+			switch {
+			case s.Allowed:
+				lr.Allowed++
+				b.Allowed++
+			case s.Status == http.StatusTooManyRequests:
+				lr.Rejected++
+				b.Rejected++
+			default:
+				lr.Errors++
+				b.Errors++
+			}
+		}
+
+		for idx := int64(0); idx <= last && len(samples) > 0; idx++ {
+			if b, ok := buckets[idx]; ok {
+				lr.Timeline = append(lr.Timeline, *b)
+			} else {
+				lr.Timeline = append(lr.Timeline, Bucket{StartMs: idx * bucket.Milliseconds()})
+			}
+		}
+
+		report.Limiters = append(report.Limiters, lr)
+	}
+
+	return report
+}
+
+// peakAllowed slides a window over the allowed samples, which are sorted by
+// offset, and returns the largest count seen.
+func peakAllowed(samples []Sample, window time.Duration) int {
+	var allowed []time.Duration
+	for _, s := range samples {
+		if s.Allowed {
+			allowed = append(allowed, s.Offset)
+		}
+	}
+
+	peak, lo := 0, 0
+	for hi := range allowed {
+		for allowed[hi]-allowed[lo] >= window {
+			lo++
+		}
+		if n := hi - lo + 1; n > peak {
+			peak = n
+		}
+	}
+	return peak
+}
+
+func WriteTable(w io.Writer, report Report) {
+	fmt.Fprintf(w, "Shape: %s, rate: %.1f req/s, duration: %v\n\n",
+		report.Shape, report.Rate, time.Duration(report.DurationMs)*time.Millisecond)
+
+	peakWindow := time.Duration(report.PeakWindowMs) * time.Millisecond
+	fmt.Fprintf(w, "%-16s %6s %8s %9s %7s %12s\n", "limiter", "sent", "allowed", "rejected", "errors", "peak/"+peakWindow.String())
+	for _, lr := range report.Limiters {
+		fmt.Fprintf(w, "%-16s %6d %8d %9d %7d %12d\n",
+			lr.Limiter, lr.Sent, lr.Allowed, lr.Rejected, lr.Errors, lr.PeakAllowed)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Allowed/rejected per bucket:")
+
+	header := []string{fmt.Sprintf("%8s", "t")}
+	for _, lr := range report.Limiters {
+		header = append(header, fmt.Sprintf("%16s", lr.Limiter))
+	}
+	fmt.Fprintln(w, strings.Join(header, " "))
+
+	for i := 0; ; i++ {
+		row := []string{fmt.Sprintf("%8s", (time.Duration(int64(i)*report.BucketMs) * time.Millisecond).String())}
+		more := false
+		for _, lr := range report.Limiters {
+			if i < len(lr.Timeline) {
+				more = true
+				b := lr.Timeline[i]
+				row = append(row, fmt.Sprintf("%16s", fmt.Sprintf("%d/%d", b.Allowed, b.Rejected)))
+			} else {
+				row = append(row, fmt.Sprintf("%16s", "-"))
+			}
+		}
+		if !more {
+			break
+		}
+		fmt.Fprintln(w, strings.Join(row, " "))
+	}
+}
+
+func WriteCSV(w io.Writer, report Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"limiter", "start_ms", "allowed", "rejected", "errors"})
+
+	for _, lr := range report.Limiters {
+		for _, b := range lr.Timeline {
+			cw.Write([]string{
+				lr.Limiter,
+				strconv.FormatInt(b.StartMs, 10),
+				strconv.Itoa(b.Allowed),
+				strconv.Itoa(b.Rejected),
+				strconv.Itoa(b.Errors),
+			})
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func WriteJSON(w io.Writer, report Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Target struct {
+	Name string
+	URL  string
+}
+
+type Sample struct {
+	Offset  time.Duration
+	Status  int
+	Allowed bool
+	Err     error
+}
+
+// Run fires the schedule at every target concurrently so all limiters see the
+// same traffic at the same time. Each request runs in its own goroutine so a
+// slow response never delays the schedule.
+func Run(client *http.Client, targets []Target, schedule []time.Duration) map[string][]Sample {
+	results := make(map[string][]Sample, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	start := time.Now()
+
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+
+			samples := runTarget(client, target, start, schedule)
+
+			mu.Lock()
+			results[target.Name] = samples
+			mu.Unlock()
+		}(target)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func runTarget(client *http.Client, target Target, start time.Time, schedule []time.Duration) []Sample {
+	samples := make([]Sample, len(schedule))
+	var wg sync.WaitGroup
+
+	for i, offset := range schedule {
+		time.Sleep(time.Until(start.Add(offset)))
+
+		wg.Add(1)
+		go func(i int, offset time.Duration) {
+			defer wg.Done()
+			samples[i] = send(client, target.URL, offset)
+		}(i, offset)
+	}
+
+	wg.Wait()
+
+	sort.SliceStable(samples, func(a, b int) bool {
+		return samples[a].Offset < samples[b].Offset
+	})
+	return samples
+}
+
+func send(client *http.Client, url string, offset time.Duration) Sample {
+	resp, err := client.Get(url)
+	if err != nil {
+		return Sample{Offset: offset, Err: err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return Sample{
+		Offset:  offset,
+		Status:  resp.StatusCode,
+		Allowed: resp.StatusCode < 400,
+	}
+}
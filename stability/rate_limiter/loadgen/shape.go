@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// maxRate keeps the interval between steady requests above zero and the
+// schedule small enough to build in memory.
+const maxRate = 1e6
+
+// ShapeConfig describes the traffic to send. Rate is the average number of
+// requests per second; Period is the cycle length of burst, sawtooth and
+// boundary shapes.
+type ShapeConfig struct {
+	Shape    string
+	Rate     float64
+	Duration time.Duration
+	Period   time.Duration
+	Burst    int
+	Seed     int64
+}
+
+// Schedule returns the send offsets from the start of the run, in order.
+func Schedule(cfg ShapeConfig) ([]time.Duration, error) {
+	if cfg.Rate <= 0 && cfg.Shape != "burst" && cfg.Shape != "boundary" {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+	if cfg.Rate > maxRate {
+		return nil, fmt.Errorf("rate must be at most %g requests per second", maxRate)
+	}
+	if cfg.Period <= 0 && cfg.Shape != "steady" && cfg.Shape != "poisson" {
+		return nil, fmt.Errorf("period must be positive")
+	}
+
+	switch cfg.Shape {
+	case "steady":
+		return steady(cfg), nil
+	case "burst":
+		return burst(cfg), nil
+	case "sawtooth":
+		return sawtooth(cfg), nil
+	case "poisson":
+		return poisson(cfg), nil
+	case "boundary":
+		return boundary(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown shape %q", cfg.Shape)
+	}
+}
+
+func steady(cfg ShapeConfig) []time.Duration {
+	interval := time.Duration(float64(time.Second) / cfg.Rate)
+
+	var offsets []time.Duration
+	for t := time.Duration(0); t < cfg.Duration; t += interval {
+		offsets = append(offsets, t)
+	}
+	return offsets
+}
+
+// burst sends Burst requests at once at the start of every Period.
+func burst(cfg ShapeConfig) []time.Duration {
+	var offsets []time.Duration
+	for t := time.Duration(0); t < cfg.Duration; t += cfg.Period {
+		for i := 0; i < cfg.Burst; i++ {
+			offsets = append(offsets, t)
+		}
+	}
+	return offsets
+}
+
+// sawtooth ramps the rate linearly from Rate/10 up to 2*Rate over every
+// Period and then drops back. The expected request count is integrated in
+// 1ms steps and a request is sent each time it crosses a whole number.
+func sawtooth(cfg ShapeConfig) []time.Duration {
+	const step = time.Millisecond
+	low, high := cfg.Rate/10, 2*cfg.Rate
+
+	var offsets []time.Duration
+	var expected float64
+	for t := time.Duration(0); t < cfg.Duration; t += step {
+		phase := float64(t%cfg.Period) / float64(cfg.Period)
+		expected += (low + (high-low)*phase) * step.Seconds()
+
+		if expected >= 1 {
+			expected--
+			offsets = append(offsets, t)
+		}
+	}
+	return offsets
+}
+
+func poisson(cfg ShapeConfig) []time.Duration {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	var offsets []time.Duration
+	for t := time.Duration(0); t < cfg.Duration; {
+		offsets = append(offsets, t)
+		t += time.Duration(rng.ExpFloat64() / cfg.Rate * float64(time.Second))
+	}
+	return offsets
+}
+
+// boundaryMargin is how close to a window edge the boundary shape fires.
+const boundaryMargin = 100 * time.Millisecond
+
+// boundary targets the fixed-window edge. After a full Period of silence,
+// so the limiter's current window has expired, one request opens a fresh
+// window; Burst requests arrive just before it closes and Burst more just
+// after the next one opens. A fixed window lets almost 2*Burst through in
+// 2*boundaryMargin; a sliding window does not. Period must match the limiter
+// window and each cycle takes 3*Period.
+func boundary(cfg ShapeConfig) []time.Duration {
+	var offsets []time.Duration
+	for cycle := time.Duration(0); cycle+3*cfg.Period <= cfg.Duration; cycle += 3 * cfg.Period {
+		opened := cycle + cfg.Period
+		offsets = append(offsets, opened)
+
+		for _, at := range []time.Duration{opened + cfg.Period - boundaryMargin, opened + cfg.Period + boundaryMargin} {
+			for i := 0; i < cfg.Burst; i++ {
+				offsets = append(offsets, at)
+			}
+		}
+	}
+	return offsets
+}
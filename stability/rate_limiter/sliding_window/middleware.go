@@ -5,10 +5,20 @@ import (
 	"net/http"
 )
 
-func RateLimitMiddleware(limiter *SlidingWindow) func(http.Handler) http.Handler {
+// DecisionObserver is told about every request the middleware admits or
+// rejects.
+type DecisionObserver func(r *http.Request, allowed bool)
+
+func RateLimitMiddleware(limiter *SlidingWindow, observers ...DecisionObserver) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !limiter.Allow() {
+			allowed := limiter.Allow()
+
+			for _, observe := range observers {
+				observe(r, allowed)
+			}
+
+			if !allowed {
 				log.Printf("Rate limit exceeded for %s", r.URL.Path)
 				w.Header().Set("X-RateLimit-Limit", "10")
 				w.Header().Set("X-RateLimit-Remaining", "0")
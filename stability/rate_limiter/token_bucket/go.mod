@@ -2,3 +2,6 @@ module token_bucket
 
 go 1.21
 
+require stability/metrics v0.0.0
+
+replace stability/metrics => ../../metrics
@@ -4,26 +4,34 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+
+	"stability/metrics"
 )
 
 func main() {
 	fmt.Println("Token Bucket Rate Limiter Demo")
-	fmt.Println("===============================\n")
+	fmt.Println("===============================")
+	fmt.Println()
 
 	limiter := NewTokenBucket(10, 5)
 
+	registry := metrics.NewRegistry()
+
 	mux := http.NewServeMux()
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Request processed successfully"))
 	})
 
-	mux.Handle("/api", RateLimitMiddleware(limiter)(handler))
+	mux.Handle("/api", RateLimitMiddleware(limiter, metrics.NewLimiterMetrics(registry).Observer("token_bucket"))(handler))
+	mux.Handle("/metrics", registry.Handler())
 
 	port := ":8081"
 	fmt.Printf("Server starting on http://localhost%s\n", port)
 	fmt.Println("Limit: 10 requests per bucket, refill rate: 5 tokens/sec")
 	fmt.Printf("Try: curl http://localhost%s/api\n", port)
+	fmt.Printf("Metrics: curl http://localhost%s/metrics\n", port)
+	fmt.Println("Compare all limiters: cd ../loadgen && go run . -shape burst")
 
 	log.Fatal(http.ListenAndServe(port, mux))
 }
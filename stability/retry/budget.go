@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryBudget is a token bucket shared by all executors calling the same
+// dependency. Every successful request deposits ratio tokens and every retry
+// withdraws one, so retries stay within ratio of recent successful traffic.
+type RetryBudget struct {
+	ratio     float64
+	maxTokens float64
+	tokens    float64
+	mu        sync.Mutex
+}
+
+func NewRetryBudget(ratio float64, maxTokens int) *RetryBudget {
+	return &RetryBudget{
+		ratio:     ratio,
+		maxTokens: float64(maxTokens),
+		tokens:    float64(maxTokens),
+	}
+}
+
+func (b *RetryBudget) OnSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+func (b *RetryBudget) AllowRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+
+	return false
+}
+
+// refund returns a token taken for a retry that never ran.
+func (b *RetryBudget) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+func (b *RetryBudget) Available() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.tokens
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/IBM/sarama"
+	"github.com/lib/pq"
+)
+
+// Classifier reports whether err is worth retrying. It has the same shape
+// as RetryConfig.ShouldRetry, so classifiers plug in directly.
+type Classifier func(err error) bool
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+func IsRetryable(err error) bool {
+	var r *retryableError
+	return errors.As(err, &r)
+}
+
+// Classify lets explicit Permanent/Retryable markers win over c.
+func Classify(c Classifier) Classifier {
+	return func(err error) bool {
+		switch {
+		case err == nil:
+			return false
+		case IsPermanent(err):
+			return false
+		case IsRetryable(err):
+			return true
+		}
+		return c(err)
+	}
+}
+
+func And(cs ...Classifier) Classifier {
+	return func(err error) bool {
+		for _, c := range cs {
+			if !c(err) {
+				return false
+			}
+		}
+		return len(cs) > 0
+	}
+}
+
+func Or(cs ...Classifier) Classifier {
+	return func(err error) bool {
+		for _, c := range cs {
+			if c(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func Not(c Classifier) Classifier {
+	return func(err error) bool {
+		return !c(err)
+	}
+}
+
+// IsTimeout reports an attempt cut off by its deadline.
+func IsTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+func NetTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func ConnectionReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func HTTPStatus(codes ...int) Classifier {
+	return func(err error) bool {
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) {
+			return false
+		}
+		for _, code := range codes {
+			if statusErr.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var RetryableHTTPStatus = HTTPStatus(
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+)
+
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+func PostgresTransient(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	switch pqErr.SQLState() {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// KafkaRetriable mirrors the broker errors sarama's own producer retries.
+func KafkaRetriable(err error) bool {
+	if errors.Is(err, sarama.ErrOutOfBrokers) {
+		return true
+	}
+
+	var kErr sarama.KError
+	if !errors.As(err, &kErr) {
+		return false
+	}
+
+	switch kErr {
+	case sarama.ErrLeaderNotAvailable,
+		sarama.ErrNotLeaderForPartition,
+		sarama.ErrRequestTimedOut,
+		sarama.ErrNotEnoughReplicas,
+		sarama.ErrNotEnoughReplicasAfterAppend,
+		sarama.ErrNetworkException,
+		sarama.ErrUnknownTopicOrPartition:
+		return true
+	}
+	return false
+}
+
+var DefaultClassifier = Classify(Or(
+	IsTimeout,
+	NetTimeout,
+	ConnectionReset,
+	RetryableHTTPStatus,
+	PostgresTransient,
+	KafkaRetriable,
+))
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Attempt struct {
+	Number   int
+	Err      error
+	Start    time.Time
+	Duration time.Duration
+	Delay    time.Duration
+}
+
+// RetryError is returned when the executor gives up. It unwraps to Cause
+// (ErrMaxAttemptsExceeded, ErrRetryBudgetExhausted or the context error when
+// the caller's deadline or MaxElapsed ran out) and to every attempt's error,
+// so errors.Is/As see the whole history.
+type RetryError struct {
+	Cause    error
+	Attempts []Attempt
+}
+
+func (e *RetryError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v after %d attempts", e.Cause, len(e.Attempts))
+
+	for _, a := range e.Attempts {
+		fmt.Fprintf(&b, "; #%d (%v): %v", a.Number, a.Duration.Round(time.Millisecond), a.Err)
+		if a.Delay > 0 {
+			fmt.Fprintf(&b, ", waited %v", a.Delay.Round(time.Millisecond))
+		}
+	}
+
+	return b.String()
+}
+
+func (e *RetryError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts)+1)
+	errs = append(errs, e.Cause)
+	for _, a := range e.Attempts {
+		errs = append(errs, a.Err)
+	}
+	return errs
+}
+
+func (e *RetryError) LastErr() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNoHedgeResult = errors.New("all hedged requests failed")
+
+type HedgeConfig struct {
+	MaxHedges int
+	// Delay is used as is when Percentile is zero, otherwise only until
+	// MinSamples latencies have been observed.
+	Delay time.Duration
+	// Percentile is a fraction in (0, 1]; larger values are clamped to 1
+	// and negative ones disable adaptive delays.
+	Percentile float64
+	MinSamples int
+	WindowSize int
+}
+
+// HedgeStats counts timer-triggered hedges separately from Relaunches, the
+// copies started because an earlier one failed.
+type HedgeStats struct {
+	Requests   int64
+	Hedges     int64
+	HedgeWins  int64
+	Relaunches int64
+}
+
+type HedgedExecutor struct {
+	config    HedgeConfig
+	latencies []time.Duration
+	next      int
+	stats     HedgeStats
+	mu        sync.Mutex
+}
+
+func NewHedgedExecutor(config HedgeConfig) *HedgedExecutor {
+	if config.MaxHedges == 0 {
+		config.MaxHedges = 1
+	}
+	if config.Delay == 0 {
+		config.Delay = 100 * time.Millisecond
+	}
+	if config.MinSamples == 0 {
+		config.MinSamples = 20
+	}
+	if config.WindowSize == 0 {
+		config.WindowSize = 200
+	}
+	config.Percentile = max(0, min(config.Percentile, 1))
+	return &HedgedExecutor{
+		config:    config,
+		latencies: make([]time.Duration, 0, config.WindowSize),
+	}
+}
+
+func (h *HedgedExecutor) Execute(ctx context.Context, fn func(context.Context) error) error {
+	_, err := Hedge(ctx, h, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Hedge starts fn and launches up to MaxHedges extra copies, one each time
+// the hedge delay passes without an answer. The first success wins and the
+// shared context cancels the rest.
+//
+// A failed copy is replaced at once, without waiting for the delay, and
+// counted as a relaunch. This is deliberate: hedging is about latency, so a
+// fast error should not cost a full delay. It also means a dependency that
+// fails fast sees MaxHedges+1 back-to-back calls; guard fn with a circuit
+// breaker when that matters.
+func Hedge[T any](ctx context.Context, h *HedgedExecutor, fn func(context.Context) (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+		hedge int
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	total := h.config.MaxHedges + 1
+	results := make(chan result, total)
+
+	launched := 0
+	timerLaunched := make([]bool, 0, total)
+	launch := func(onTimer bool) {
+		hedge := launched
+		launched++
+		timerLaunched = append(timerLaunched, onTimer)
+		go func() {
+			val, err := fn(ctx)
+			results <- result{value: val, err: err, hedge: hedge}
+		}()
+	}
+
+	h.recordRequest()
+	start := time.Now()
+	launch(false)
+
+	delay := h.HedgeDelay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var zero T
+	var lastErr error
+	finished := 0
+
+	for {
+		select {
+		case res := <-results:
+			if res.err == nil {
+				// Measured from the original request so a winning hedge
+				// doesn't make the dependency look faster than it is.
+				h.recordWin(timerLaunched[res.hedge], time.Since(start))
+				return res.value, nil
+			}
+
+			lastErr = res.err
+			finished++
+
+			if launched < total {
+				h.recordRelaunch()
+				launch(false)
+				timer.Reset(delay)
+			} else if finished == launched {
+				return zero, errors.Join(ErrNoHedgeResult, lastErr)
+			}
+
+		case <-timer.C:
+			if launched < total {
+				h.recordHedge()
+				launch(true)
+				timer.Reset(delay)
+			}
+
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+func (h *HedgedExecutor) HedgeDelay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.config.Percentile <= 0 || len(h.latencies) < h.config.MinSamples {
+		return h.config.Delay
+	}
+
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(float64(len(sorted)-1) * h.config.Percentile)
+	return sorted[idx]
+}
+
+func (h *HedgedExecutor) Stats() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats
+}
+
+func (h *HedgedExecutor) recordRequest() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stats.Requests++
+}
+
+func (h *HedgedExecutor) recordHedge() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stats.Hedges++
+}
+
+func (h *HedgedExecutor) recordRelaunch() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stats.Relaunches++
+}
+
+func (h *HedgedExecutor) recordWin(hedged bool, elapsed time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if hedged {
+		h.stats.HedgeWins++
+	}
+
+	if len(h.latencies) < h.config.WindowSize {
+		h.latencies = append(h.latencies, elapsed)
+		return
+	}
+	h.latencies[h.next] = elapsed
+	h.next = (h.next + 1) % h.config.WindowSize
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+	retryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.retryAfter > 0 {
+		return fmt.Sprintf("http status %s (retry after %v)", e.Status, e.retryAfter)
+	}
+	return "http status " + e.Status
+}
+
+func (e *HTTPStatusError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// CheckResponse converts an error response into *HTTPStatusError, keeping the
+// Retry-After hint so RetryExecutor waits as long as the server asked.
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	return &HTTPStatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
@@ -3,17 +3,47 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"stability/faultinjection"
+	"stability/metrics"
 )
 
 func main() {
 	fmt.Println("Retry Pattern Demo")
-	fmt.Println("======================\n")
+	fmt.Println("======================")
+	fmt.Println()
 
 	ctx := context.Background()
 
-	apiClient := NewExternalAPIClient(0.7)
+	faults := faultinjection.NewRegistry()
+	apiFaults := faults.Register(faultinjection.New("external-api", faultinjection.Config{
+		ErrorRate: 0.7,
+		HangRate:  0.2,
+		Seed:      42,
+	}))
+
+	apiClient := NewExternalAPIClient(apiFaults)
+
+	budget := NewRetryBudget(0.1, 10)
+
+	registry := metrics.NewRegistry()
 
-	dataService := NewDataService(apiClient)
+	dataService := NewDataService(apiClient, budget, retryMetrics(registry, "get_data", budget))
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle(faultinjection.AdminPath, faults.AdminHandler(faultinjection.AdminPath))
+		mux.Handle("/metrics", registry.Handler())
+		if err := http.ListenAndServe(":9091", mux); err != nil {
+			log.Printf("Admin server stopped: %v", err)
+		}
+	}()
+	fmt.Printf("Fault admin: http://localhost:9091%s\n", faultinjection.AdminPath)
+	fmt.Println("Metrics: curl http://localhost:9091/metrics")
+	fmt.Println()
 
 	fmt.Println("Example 1:")
 	dataService.GetData(ctx, "user-123")
@@ -27,4 +57,11 @@ func main() {
 
 	fmt.Println("\nExample 3:")
 	dataService.GetData(ctx, "product-789")
+
+	fmt.Println("\n" + string(make([]byte, 50)))
+
+	fmt.Println("\nExample 4 (hedged):")
+	dataService.GetDataHedged(ctx, "catalog-001")
+	fmt.Println()
+	registry.WriteText(os.Stdout)
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+
+	"stability/metrics"
+)
+
+func retryMetrics(registry *metrics.Registry, operation string, budget *RetryBudget) func(attempts int, err error) {
+	attempts := registry.NewHistogram("retry_attempts",
+		"Attempts made per retried execution.", []float64{1, 2, 3, 4, 5}, "operation", "result")
+	registry.NewGaugeFunc("retry_budget_tokens",
+		"Retry tokens currently available in the shared budget.", budget.Available)
+
+	return func(n int, err error) {
+		result := "success"
+		switch {
+		case errors.Is(err, ErrRetryBudgetExhausted):
+			result = "budget_exhausted"
+		case err != nil:
+			result = "failure"
+		}
+		attempts.Observe(float64(n), operation, result)
+	}
+}
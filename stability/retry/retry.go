@@ -6,19 +6,49 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sync"
 	"time"
 )
 
-var ErrMaxAttemptsExceeded = errors.New("max retry attempts exceeded")
+var (
+	ErrMaxAttemptsExceeded = errors.New("max retry attempts exceeded")
+	ErrRetryAfterTooLong   = errors.New("server retry-after exceeds remaining deadline")
+)
 
 type Strategy interface {
-	NextDelay(attempt int) time.Duration
+	NextDelay(attempt int, lastErr error) time.Duration
+}
+
+// StatefulStrategy is implemented by strategies that remember previous
+// delays. The executor calls New once per execution so concurrent calls
+// never share that state.
+type StatefulStrategy interface {
+	Strategy
+	New() Strategy
+}
+
+// RetryAfterError is implemented by errors carrying a server-provided hint
+// (Retry-After header, gRPC pushback) that overrides the strategy delay.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
 }
 
 type RetryConfig struct {
 	MaxAttempts int
 	Strategy    Strategy
 	ShouldRetry func(error) bool
+	Budget      *RetryBudget
+
+	AttemptTimeout time.Duration
+	MaxElapsed     time.Duration
+
+	// MaxRetryAfter caps a server-provided Retry-After hint.
+	MaxRetryAfter time.Duration
+
+	// OnDone, if set, is called once per execution with the number of
+	// attempts made and the final error.
+	OnDone func(attempts int, err error)
 }
 
 type RetryExecutor struct {
@@ -32,52 +62,105 @@ func NewRetryExecutor(config RetryConfig) *RetryExecutor {
 	if config.Strategy == nil {
 		config.Strategy = NewExponentialBackoff(100*time.Millisecond, 5*time.Second, 2.0)
 	}
+	if config.MaxRetryAfter == 0 {
+		config.MaxRetryAfter = 30 * time.Second
+	}
 	if config.ShouldRetry == nil {
+		// By default every error is retried. A caller-supplied
+		// ShouldRetry decides alone, so attempt timeouts are only
+		// retried if it says so, e.g. Or(IsTimeout, myClassifier).
 		config.ShouldRetry = func(err error) bool { return err != nil }
 	}
+	// Permanent/Retryable markers always win.
+	config.ShouldRetry = Classify(config.ShouldRetry)
 	return &RetryExecutor{config: config}
 }
 
-func (r *RetryExecutor) Execute(fn func() error) error {
-	var lastErr error
+type Option func(*executeOptions)
 
-	for attempt := 1; attempt <= r.config.MaxAttempts; attempt++ {
-		err := fn()
-		if err == nil {
-			return nil
-		}
-
-		lastErr = err
+type executeOptions struct {
+	onAttempt      func(attempt int)
+	onRetry        func(attempt int, err error, delay time.Duration)
+	attemptTimeout time.Duration
+}
 
-		if !r.config.ShouldRetry(err) {
-			return err
-		}
+func WithOnAttempt(fn func(attempt int)) Option {
+	return func(o *executeOptions) {
+		o.onAttempt = fn
+	}
+}
 
-		if attempt == r.config.MaxAttempts {
-			break
-		}
+func WithOnRetry(fn func(attempt int, err error, delay time.Duration)) Option {
+	return func(o *executeOptions) {
+		o.onRetry = fn
+	}
+}
 
-		delay := r.config.Strategy.NextDelay(attempt)
-		time.Sleep(delay)
+func WithAttemptTimeout(timeout time.Duration) Option {
+	return func(o *executeOptions) {
+		o.attemptTimeout = timeout
 	}
+}
 
-	return fmt.Errorf("%w: %v", ErrMaxAttemptsExceeded, lastErr)
+func (r *RetryExecutor) Execute(fn func() error, opts ...Option) error {
+	return r.ExecuteWithContext(context.Background(), func(context.Context) error {
+		return fn()
+	}, opts...)
 }
 
-func (r *RetryExecutor) ExecuteWithContext(ctx context.Context, fn func(context.Context) error) error {
-	var lastErr error
+func (r *RetryExecutor) ExecuteWithContext(ctx context.Context, fn func(context.Context) error, opts ...Option) (err error) {
+	o := executeOptions{attemptTimeout: r.config.AttemptTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var attempts int
+	if r.config.OnDone != nil {
+		defer func() { r.config.OnDone(attempts, err) }()
+	}
+
+	if r.config.MaxElapsed > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.config.MaxElapsed)
+		defer cancel()
+	}
+
+	strategy := r.config.Strategy
+	if stateful, ok := strategy.(StatefulStrategy); ok {
+		strategy = stateful.New()
+	}
+
+	var history []Attempt
 
 	for attempt := 1; attempt <= r.config.MaxAttempts; attempt++ {
-		if err := ctx.Err(); err != nil {
-			return err
+		if ctx.Err() != nil {
+			return &RetryError{Cause: ctx.Err(), Attempts: history}
+		}
+
+		attempts = attempt
+		if o.onAttempt != nil {
+			o.onAttempt(attempt)
 		}
 
-		err := fn(ctx)
+		start := time.Now()
+		err := r.runAttempt(ctx, fn, o.attemptTimeout)
 		if err == nil {
+			r.recordSuccess()
 			return nil
 		}
 
-		lastErr = err
+		history = append(history, Attempt{
+			Number:   attempt,
+			Err:      err,
+			Start:    start,
+			Duration: time.Since(start),
+		})
+
+		// The caller's context or MaxElapsed ran out: that is not a
+		// retryable failure and must not cost a budget token.
+		if ctx.Err() != nil {
+			return &RetryError{Cause: ctx.Err(), Attempts: history}
+		}
 
 		if !r.config.ShouldRetry(err) {
 			return err
@@ -87,50 +170,111 @@ func (r *RetryExecutor) ExecuteWithContext(ctx context.Context, fn func(context.
 			break
 		}
 
-		delay := r.config.Strategy.NextDelay(attempt)
+		delay := strategy.NextDelay(attempt, err)
+
+		var hint RetryAfterError
+		if errors.As(err, &hint) && hint.RetryAfter() > 0 {
+			delay = min(hint.RetryAfter(), r.config.MaxRetryAfter)
+
+			// No point waiting for a server that will only be ready
+			// after the caller has given up.
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return &RetryError{Cause: ErrRetryAfterTooLong, Attempts: history}
+			}
+		}
+
+		if !r.allowRetry() {
+			return &RetryError{Cause: ErrRetryBudgetExhausted, Attempts: history}
+		}
+
+		history[len(history)-1].Delay = delay
+
+		if o.onRetry != nil {
+			o.onRetry(attempt, err, delay)
+		}
 
 		select {
 		case <-time.After(delay):
 		case <-ctx.Done():
-			return ctx.Err()
+			r.refundRetry()
+			return &RetryError{Cause: ctx.Err(), Attempts: history}
 		}
 	}
 
-	return fmt.Errorf("%w: %v", ErrMaxAttemptsExceeded, lastErr)
+	return &RetryError{Cause: ErrMaxAttemptsExceeded, Attempts: history}
 }
 
-func (r *RetryExecutor) ExecuteWithCallback(
-	fn func() error,
-	onRetry func(attempt int, err error, delay time.Duration),
-) error {
-	var lastErr error
+func Do[T any](ctx context.Context, r *RetryExecutor, fn func(context.Context) (T, error), opts ...Option) (T, error) {
+	var result T
 
-	for attempt := 1; attempt <= r.config.MaxAttempts; attempt++ {
-		err := fn()
-		if err == nil {
-			return nil
+	err := r.ExecuteWithContext(ctx, func(ctx context.Context) error {
+		val, err := fn(ctx)
+		if err != nil {
+			return err
 		}
+		result = val
+		return nil
+	}, opts...)
 
-		lastErr = err
+	if err != nil {
+		var zero T
+		return zero, err
+	}
 
-		if !r.config.ShouldRetry(err) {
+	return result, nil
+}
+
+// runAttempt stops waiting for fn once the attempt deadline or the caller's
+// context ends, even if fn ignores its context; the abandoned call finishes
+// in the background.
+func (r *RetryExecutor) runAttempt(ctx context.Context, fn func(context.Context) error, timeout time.Duration) error {
+	attemptCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- fn(attemptCtx)
+	}()
+
+	select {
+	case err := <-errChan:
+		if err == nil || ctx.Err() != nil {
 			return err
 		}
 
-		if attempt == r.config.MaxAttempts {
-			break
+		// Only the attempt's own deadline fired. Make sure the error says
+		// so even if fn returned something else once its context ended.
+		if errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
 		}
+		return err
 
-		delay := r.config.Strategy.NextDelay(attempt)
-
-		if onRetry != nil {
-			onRetry(attempt, err, delay)
+	case <-attemptCtx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
+		return fmt.Errorf("attempt abandoned after %v: %w", timeout, context.DeadlineExceeded)
+	}
+}
 
-		time.Sleep(delay)
+func (r *RetryExecutor) recordSuccess() {
+	if r.config.Budget != nil {
+		r.config.Budget.OnSuccess()
 	}
+}
+
+func (r *RetryExecutor) allowRetry() bool {
+	return r.config.Budget == nil || r.config.Budget.AllowRetry()
+}
 
-	return fmt.Errorf("%w: %v", ErrMaxAttemptsExceeded, lastErr)
+func (r *RetryExecutor) refundRetry() {
+	if r.config.Budget != nil {
+		r.config.Budget.refund()
+	}
 }
 
 type ExponentialBackoff struct {
@@ -147,7 +291,7 @@ func NewExponentialBackoff(initial, max time.Duration, multiplier float64) *Expo
 	}
 }
 
-func (e *ExponentialBackoff) NextDelay(attempt int) time.Duration {
+func (e *ExponentialBackoff) NextDelay(attempt int, lastErr error) time.Duration {
 	delay := float64(e.InitialDelay) * math.Pow(e.Multiplier, float64(attempt-1))
 	if delay > float64(e.MaxDelay) {
 		delay = float64(e.MaxDelay)
@@ -163,7 +307,7 @@ func NewFixedDelay(delay time.Duration) *FixedDelay {
 	return &FixedDelay{Delay: delay}
 }
 
-func (f *FixedDelay) NextDelay(attempt int) time.Duration {
+func (f *FixedDelay) NextDelay(attempt int, lastErr error) time.Duration {
 	return f.Delay
 }
 
@@ -181,7 +325,7 @@ func NewLinearBackoff(initial, increment, max time.Duration) *LinearBackoff {
 	}
 }
 
-func (l *LinearBackoff) NextDelay(attempt int) time.Duration {
+func (l *LinearBackoff) NextDelay(attempt int, lastErr error) time.Duration {
 	delay := l.InitialDelay + time.Duration(attempt-1)*l.Increment
 	if delay > l.MaxDelay {
 		delay = l.MaxDelay
@@ -205,7 +349,7 @@ func NewExponentialBackoffWithJitter(initial, max time.Duration, multiplier, jit
 	}
 }
 
-func (e *ExponentialBackoffWithJitter) NextDelay(attempt int) time.Duration {
+func (e *ExponentialBackoffWithJitter) NextDelay(attempt int, lastErr error) time.Duration {
 	delay := float64(e.InitialDelay) * math.Pow(e.Multiplier, float64(attempt-1))
 	if delay > float64(e.MaxDelay) {
 		delay = float64(e.MaxDelay)
@@ -235,7 +379,7 @@ func NewFullJitter(base, max time.Duration, multiplier float64) *FullJitter {
 	}
 }
 
-func (f *FullJitter) NextDelay(attempt int) time.Duration {
+func (f *FullJitter) NextDelay(attempt int, lastErr error) time.Duration {
 	maxDelay := float64(f.BaseDelay) * math.Pow(f.Multiplier, float64(attempt-1))
 	if maxDelay > float64(f.MaxDelay) {
 		maxDelay = float64(f.MaxDelay)
@@ -243,3 +387,118 @@ func (f *FullJitter) NextDelay(attempt int) time.Duration {
 
 	return time.Duration(rand.Float64() * maxDelay)
 }
+
+type jitterRand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newJitterRand(seed int64) *jitterRand {
+	return &jitterRand{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (j *jitterRand) Float64() float64 {
+	if j == nil {
+		return rand.Float64()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.rand.Float64()
+}
+
+func (j *jitterRand) fork() *jitterRand {
+	if j == nil {
+		return newJitterRand(rand.Int63())
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return newJitterRand(j.rand.Int63())
+}
+
+type DecorrelatedJitter struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	rand      *jitterRand
+	prev      time.Duration
+}
+
+func NewDecorrelatedJitter(base, max time.Duration) *DecorrelatedJitter {
+	return &DecorrelatedJitter{
+		BaseDelay: base,
+		MaxDelay:  max,
+	}
+}
+
+func (d *DecorrelatedJitter) WithSeed(seed int64) *DecorrelatedJitter {
+	d.rand = newJitterRand(seed)
+	return d
+}
+
+func (d *DecorrelatedJitter) New() Strategy {
+	return &DecorrelatedJitter{
+		BaseDelay: d.BaseDelay,
+		MaxDelay:  d.MaxDelay,
+		rand:      d.rand.fork(),
+	}
+}
+
+// NextDelay picks uniformly from [base, prev*3] and caps it at MaxDelay.
+func (d *DecorrelatedJitter) NextDelay(attempt int, lastErr error) time.Duration {
+	prev := d.prev
+	if prev < d.BaseDelay {
+		prev = d.BaseDelay
+	}
+
+	upper := float64(prev) * 3
+	delay := float64(d.BaseDelay) + d.rand.Float64()*(upper-float64(d.BaseDelay))
+	if delay > float64(d.MaxDelay) {
+		delay = float64(d.MaxDelay)
+	}
+
+	d.prev = time.Duration(delay)
+	return d.prev
+}
+
+type EqualJitter struct {
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Multiplier float64
+	rand       *jitterRand
+}
+
+func NewEqualJitter(base, max time.Duration, multiplier float64) *EqualJitter {
+	return &EqualJitter{
+		BaseDelay:  base,
+		MaxDelay:   max,
+		Multiplier: multiplier,
+	}
+}
+
+func (e *EqualJitter) WithSeed(seed int64) *EqualJitter {
+	e.rand = newJitterRand(seed)
+	return e
+}
+
+func (e *EqualJitter) New() Strategy {
+	return &EqualJitter{
+		BaseDelay:  e.BaseDelay,
+		MaxDelay:   e.MaxDelay,
+		Multiplier: e.Multiplier,
+		rand:       e.rand.fork(),
+	}
+}
+
+// NextDelay keeps half of the exponential delay and randomises the other half.
+func (e *EqualJitter) NextDelay(attempt int, lastErr error) time.Duration {
+	delay := float64(e.BaseDelay) * math.Pow(e.Multiplier, float64(attempt-1))
+	if delay > float64(e.MaxDelay) {
+		delay = float64(e.MaxDelay)
+	}
+
+	half := delay / 2
+	return time.Duration(half + e.rand.Float64()*half)
+}
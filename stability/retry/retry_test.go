@@ -0,0 +1,161 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecorrelatedJitterBounds(t *testing.T) {
+	base, max := 10*time.Millisecond, time.Second
+	strategy := NewDecorrelatedJitter(base, max).WithSeed(1).New()
+
+	prev := time.Duration(0)
+	for attempt := 1; attempt <= 1000; attempt++ {
+		delay := strategy.NextDelay(attempt, nil)
+
+		upper := 3 * prev
+		if prev < base {
+			upper = 3 * base
+		}
+		if upper > max {
+			upper = max
+		}
+
+		if delay < base || delay > upper {
+			t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, delay, base, upper)
+		}
+		prev = delay
+	}
+}
+
+func TestEqualJitterBounds(t *testing.T) {
+	base, max := 10*time.Millisecond, time.Second
+	strategy := NewEqualJitter(base, max, 2).WithSeed(1).New()
+
+	for attempt := 1; attempt <= 20; attempt++ {
+		d := base << (attempt - 1)
+		if d > max || d <= 0 {
+			d = max
+		}
+
+		for i := 0; i < 100; i++ {
+			delay := strategy.NextDelay(attempt, nil)
+			if delay < d/2 || delay > d {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, delay, d/2, d)
+			}
+		}
+	}
+}
+
+func TestSeededJitterIsDeterministic(t *testing.T) {
+	a := NewDecorrelatedJitter(10*time.Millisecond, time.Second).WithSeed(7).New()
+	b := NewDecorrelatedJitter(10*time.Millisecond, time.Second).WithSeed(7).New()
+
+	for attempt := 1; attempt <= 50; attempt++ {
+		if da, db := a.NextDelay(attempt, nil), b.NextDelay(attempt, nil); da != db {
+			t.Fatalf("attempt %d: same seed gave %v and %v", attempt, da, db)
+		}
+	}
+}
+
+func TestNewDoesNotShareState(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent func() StatefulStrategy
+	}{
+		{"decorrelated", func() StatefulStrategy {
+			return NewDecorrelatedJitter(10*time.Millisecond, time.Second).WithSeed(42)
+		}},
+		{"equal", func() StatefulStrategy {
+			return NewEqualJitter(10*time.Millisecond, time.Second, 2).WithSeed(42)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Two parents with the same seed hand out the same children.
+			// Draining the first child of one parent must not change what
+			// its sibling returns.
+			left, right := tt.parent(), tt.parent()
+			drained, sibling := left.New(), left.New()
+			_, reference := right.New(), right.New()
+
+			for attempt := 1; attempt <= 100; attempt++ {
+				drained.NextDelay(attempt, nil)
+			}
+
+			for attempt := 1; attempt <= 20; attempt++ {
+				if got, want := sibling.NextDelay(attempt, nil), reference.NextDelay(attempt, nil); got != want {
+					t.Fatalf("attempt %d: sibling returned %v, want %v", attempt, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitterNewStartsFresh(t *testing.T) {
+	base, maxDelay := 10*time.Millisecond, time.Second
+	parent := NewDecorrelatedJitter(base, maxDelay).WithSeed(3)
+
+	for i := 0; i < 50; i++ {
+		used := parent.New()
+		for attempt := 1; attempt <= 20; attempt++ {
+			used.NextDelay(attempt, nil)
+		}
+
+		// A fresh instance has no previous delay, so its first delay
+		// comes from [base, 3*base] no matter what its siblings did.
+		if delay := parent.New().NextDelay(1, nil); delay < base || delay > 3*base {
+			t.Fatalf("fresh instance returned %v, want within [%v, %v]", delay, base, 3*base)
+		}
+	}
+}
+
+func TestEqualJitterDistribution(t *testing.T) {
+	const samples = 10000
+	base, maxDelay := 10*time.Millisecond, time.Second
+	strategy := NewEqualJitter(base, maxDelay, 2).WithSeed(11).New()
+
+	d := 4 * base // attempt 3
+	var sum float64
+	lowest, highest := d, time.Duration(0)
+	for i := 0; i < samples; i++ {
+		delay := strategy.NextDelay(3, nil)
+		sum += float64(delay)
+		lowest = min(lowest, delay)
+		highest = max(highest, delay)
+	}
+
+	// Uniform over [d/2, d]: the mean is 0.75*d and samples reach both ends.
+	if mean := sum / samples / float64(d); mean < 0.74 || mean > 0.76 {
+		t.Errorf("mean is %.3f*d, want about 0.75*d", mean)
+	}
+	if lowest > d*52/100 || highest < d*98/100 {
+		t.Errorf("samples span [%v, %v], want close to [%v, %v]", lowest, highest, d/2, d)
+	}
+}
+
+func TestDecorrelatedJitterDistribution(t *testing.T) {
+	const samples = 10000
+	base, maxDelay := 10*time.Millisecond, time.Hour
+	parent := NewDecorrelatedJitter(base, maxDelay).WithSeed(5)
+
+	// Place every second delay within its range [base, 3*prev], where prev
+	// is the first delay, and count it into one of four equal buckets. A
+	// uniform spread puts about a quarter of the samples into each.
+	var buckets [4]int
+	for i := 0; i < samples; i++ {
+		strategy := parent.New()
+		prev := strategy.NextDelay(1, nil)
+		delay := strategy.NextDelay(2, nil)
+
+		position := float64(delay-base) / float64(3*prev-base)
+		buckets[min(int(position*4), 3)]++
+	}
+
+	for i, n := range buckets {
+		if share := float64(n) / samples; share < 0.23 || share > 0.27 {
+			t.Errorf("bucket %d holds %.3f of samples, want about 0.25 (%v)", i, share, buckets)
+		}
+	}
+}
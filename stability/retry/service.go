@@ -3,23 +3,29 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"time"
+
+	"stability/faultinjection"
 )
 
 type ExternalAPIClient struct {
-	failureRate float32
+	faults *faultinjection.Injector
 }
 
-func NewExternalAPIClient(failureRate float32) *ExternalAPIClient {
+func NewExternalAPIClient(faults *faultinjection.Injector) *ExternalAPIClient {
 	return &ExternalAPIClient{
-		failureRate: failureRate,
+		faults: faults,
 	}
 }
 
-func (c *ExternalAPIClient) GetData(id string) (string, error) {
-	if time.Now().UnixNano()%100 < int64(c.failureRate*100) {
-		return "", errors.New("network error: connection timeout")
+func (c *ExternalAPIClient) GetData(ctx context.Context, id string) (string, error) {
+	if err := c.faults.Inject(ctx); err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("network error: %w", err)
 	}
 
 	return "data for " + id, nil
@@ -28,51 +34,90 @@ func (c *ExternalAPIClient) GetData(id string) (string, error) {
 type DataService struct {
 	apiClient *ExternalAPIClient
 	retry     *RetryExecutor
+	hedge     *HedgedExecutor
 }
 
-func NewDataService(apiClient *ExternalAPIClient) *DataService {
+func NewDataService(apiClient *ExternalAPIClient, budget *RetryBudget, onDone func(attempts int, err error)) *DataService {
 	retryConfig := RetryConfig{
 		MaxAttempts: 5,
 		Strategy:    NewExponentialBackoff(100*time.Millisecond, 5*time.Second, 2.0),
+		Budget:      budget,
+
+		AttemptTimeout: 500 * time.Millisecond,
+		MaxElapsed:     10 * time.Second,
+
+		OnDone: onDone,
 	}
 
 	return &DataService{
 		apiClient: apiClient,
 		retry:     NewRetryExecutor(retryConfig),
+		hedge: NewHedgedExecutor(HedgeConfig{
+			MaxHedges:  2,
+			Delay:      50 * time.Millisecond,
+			Percentile: 0.95,
+		}),
 	}
 }
 
 func (s *DataService) GetData(ctx context.Context, id string) (string, error) {
 	log.Printf("Getting data for ID: %s", id)
 
-	var result string
 	var attempts int
 
-	err := s.retry.ExecuteWithCallback(
-		func() error {
-			attempts++
-			log.Printf("  Attempt %d...", attempts)
-
-			data, err := s.apiClient.GetData(id)
+	result, err := Do(ctx, s.retry,
+		func(ctx context.Context) (string, error) {
+			data, err := s.apiClient.GetData(ctx, id)
 			if err != nil {
 				log.Printf("    Failed: %v", err)
-				return err
+				return "", err
 			}
 
-			result = data
 			log.Printf("    Success!")
-			return nil
+			return data, nil
 		},
-		func(attempt int, err error, delay time.Duration) {
+		WithOnAttempt(func(attempt int) {
+			attempts = attempt
+			log.Printf("  Attempt %d...", attempt)
+		}),
+		WithOnRetry(func(attempt int, err error, delay time.Duration) {
 			log.Printf("  Retrying in %v...", delay)
-		},
+		}),
 	)
 
+	if errors.Is(err, ErrRetryBudgetExhausted) {
+		log.Printf("Retry budget exhausted, failing fast")
+		return "", err
+	}
+
 	if err != nil {
-		log.Printf("All attempts failed")
+		log.Printf("All attempts failed: %v", err)
 		return "", err
 	}
 
 	log.Printf("Data retrieved after %d attempts: %s\n", attempts, result)
 	return result, nil
 }
+
+func (s *DataService) GetDataHedged(ctx context.Context, id string) (string, error) {
+	log.Printf("Getting data for ID: %s (hedge after %v)", id, s.hedge.HedgeDelay())
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	result, err := Hedge(ctx, s.hedge, func(ctx context.Context) (string, error) {
+		return s.apiClient.GetData(ctx, id)
+	})
+
+	stats := s.hedge.Stats()
+	log.Printf("  Hedge stats: requests=%d, hedges=%d, hedge wins=%d, relaunches=%d",
+		stats.Requests, stats.Hedges, stats.HedgeWins, stats.Relaunches)
+
+	if err != nil {
+		log.Printf("  Hedged request failed: %v", err)
+		return "", err
+	}
+
+	log.Printf("  Data retrieved: %s", result)
+	return result, nil
+}
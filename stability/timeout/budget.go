@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineHeader carries the caller's remaining budget in milliseconds.
+// A relative value avoids depending on clocks being in sync across hosts.
+const DeadlineHeader = "X-Request-Deadline"
+
+type budgetStage struct {
+	name   string
+	weight float64
+}
+
+// DeadlineBudget splits whatever is left of an incoming deadline across
+// ordered stages by weight. Time saved by early stages goes to later ones.
+type DeadlineBudget struct {
+	stages []budgetStage
+}
+
+func NewDeadlineBudget() *DeadlineBudget {
+	return &DeadlineBudget{}
+}
+
+func (b *DeadlineBudget) AddStage(name string, weight float64) *DeadlineBudget {
+	b.stages = append(b.stages, budgetStage{name: name, weight: weight})
+	return b
+}
+
+func (b *DeadlineBudget) StageTimeout(ctx context.Context, name string) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	idx := -1
+	for i, stage := range b.stages {
+		if stage.name == name {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return 0, false
+	}
+
+	var remainingWeight float64
+	for _, stage := range b.stages[idx:] {
+		remainingWeight += stage.weight
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 || remainingWeight <= 0 {
+		return 0, true
+	}
+
+	return time.Duration(float64(remaining) * b.stages[idx].weight / remainingWeight), true
+}
+
+// StageContext derives the context for one stage. Without a parent deadline
+// or for an unknown stage it only adds cancellation.
+func (b *DeadlineBudget) StageContext(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	timeout, ok := b.StageTimeout(ctx, name)
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeoutCause(ctx, timeout, BudgetExpired("stage "+name, timeout))
+}
+
+func InjectDeadline(req *http.Request) {
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return
+	}
+
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 0 {
+		remaining = 0
+	}
+	req.Header.Set(DeadlineHeader, strconv.FormatInt(remaining, 10))
+}
+
+type DeadlineTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *DeadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	req = req.Clone(req.Context())
+	InjectDeadline(req)
+
+	return base.RoundTrip(req)
+}
+
+func DeadlineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ms, err := strconv.ParseInt(r.Header.Get(DeadlineHeader), 10, 64)
+		if err != nil || ms < 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		budget := time.Duration(ms) * time.Millisecond
+		ctx, cancel := context.WithTimeoutCause(r.Context(), budget, BudgetExpired("upstream request", budget))
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -2,3 +2,11 @@ module stability/timeout
 
 go 1.21
 
+require (
+	stability/faultinjection v0.0.0
+	stability/metrics v0.0.0
+)
+
+replace stability/faultinjection => ../faultinjection
+
+replace stability/metrics => ../metrics
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	histogramMin     = time.Millisecond
+	histogramGrowth  = 1.2
+	histogramBuckets = 64
+)
+
+// latencyHistogram is a streaming histogram with exponential buckets. Once
+// it holds window samples all counts are halved, so old latencies fade out.
+type latencyHistogram struct {
+	counts [histogramBuckets]float64
+	total  float64
+	window float64
+}
+
+func newLatencyHistogram(window int) *latencyHistogram {
+	return &latencyHistogram{window: float64(window)}
+}
+
+func (h *latencyHistogram) Add(d time.Duration) {
+	h.counts[bucketFor(d)]++
+	h.total++
+
+	if h.total >= h.window {
+		h.total = 0
+		for i := range h.counts {
+			h.counts[i] /= 2
+			h.total += h.counts[i]
+		}
+	}
+}
+
+// Quantile returns the upper bound of the bucket holding quantile q.
+func (h *latencyHistogram) Quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	target := q * h.total
+	var seen float64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			return bucketUpperBound(i)
+		}
+	}
+
+	return bucketUpperBound(histogramBuckets - 1)
+}
+
+func bucketFor(d time.Duration) int {
+	if d <= histogramMin {
+		return 0
+	}
+
+	i := int(math.Ceil(math.Log(float64(d)/float64(histogramMin)) / math.Log(histogramGrowth)))
+	if i >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+	return i
+}
+
+func bucketUpperBound(i int) time.Duration {
+	return time.Duration(float64(histogramMin) * math.Pow(histogramGrowth, float64(i)))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TimeoutConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	RequestTimeout        time.Duration
+}
+
+func DefaultTimeoutConfig() TimeoutConfig {
+	return TimeoutConfig{
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+
+		DialTimeout:           2 * time.Second,
+		TLSHandshakeTimeout:   3 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		RequestTimeout:        10 * time.Second,
+	}
+}
+
+func NewHTTPServer(addr string, handler http.Handler, config TimeoutConfig) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           DeadlineMiddleware(handler),
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+}
+
+func NewHTTPClient(config TimeoutConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		IdleConnTimeout:       config.IdleTimeout,
+		MaxIdleConnsPerHost:   10,
+	}
+
+	return &http.Client{
+		Transport: &DeadlineTransport{Base: transport},
+		Timeout:   config.RequestTimeout,
+	}
+}
+
+var handlerTimeouts atomic.Int64
+
+// HandlerTimeouts reports how many 504s TimeoutMiddleware has sent.
+func HandlerTimeouts() int64 {
+	return handlerTimeouts.Load()
+}
+
+// TimeoutMiddleware is like http.TimeoutHandler but answers 504 with a JSON
+// body. The handler writes into a buffer that is discarded on timeout.
+func TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeoutCause(r.Context(), timeout, BudgetExpired("handler", timeout))
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicChan := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p)
+
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				for k, v := range tw.header {
+					w.Header()[k] = v
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				w.Write(tw.buf.Bytes())
+
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				tw.timedOut = true
+				handlerTimeouts.Add(1)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error":  "handler timeout",
+					"detail": context.Cause(ctx).Error(),
+				})
+			}
+		})
+	}
+}
+
+type timeoutWriter struct {
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+	mu       sync.Mutex
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
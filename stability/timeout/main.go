@@ -3,16 +3,50 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
 	"time"
+
+	"stability/faultinjection"
+	"stability/metrics"
 )
 
 func main() {
 	fmt.Println("Timeout Pattern Demo")
-	fmt.Println("====================\n")
+	fmt.Println("====================")
+	fmt.Println()
 
 	ctx := context.Background()
 
-	dbClient := NewDatabaseClient(2 * time.Second)
+	faults := faultinjection.NewRegistry()
+	dbFaults := faults.Register(faultinjection.New("database", faultinjection.Config{
+		Latency: faultinjection.LatencyConfig{
+			Distribution: faultinjection.DistributionUniform,
+			Min:          faultinjection.Duration(2 * time.Second),
+			Max:          faultinjection.Duration(4 * time.Second),
+		},
+		Seed: 42,
+	}))
+
+	registry := metrics.NewRegistry()
+	timeoutMetrics(registry)
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle(faultinjection.AdminPath, faults.AdminHandler(faultinjection.AdminPath))
+		mux.Handle("/metrics", registry.Handler())
+		if err := http.ListenAndServe(":9092", mux); err != nil {
+			log.Printf("Admin server stopped: %v", err)
+		}
+	}()
+	fmt.Printf("Fault admin: http://localhost:9092%s\n", faultinjection.AdminPath)
+	fmt.Println("Metrics: curl http://localhost:9092/metrics")
+	fmt.Println()
+
+	dbClient := NewDatabaseClient(dbFaults)
 
 	userService := NewUserService(dbClient, 3*time.Second)
 
@@ -30,4 +64,97 @@ func main() {
 
 	fmt.Println("\nExample 3: Slow query with short timeout (should timeout)")
 	fastService.GetUser(ctx, "789")
+
+	fmt.Println(string(make([]byte, 50)))
+
+	fmt.Println("\nExample 4: Request budget split across stages")
+	reqCtx, cancel := context.WithTimeout(ctx, 6*time.Second)
+	defer cancel()
+
+	budget := NewDeadlineBudget().
+		AddStage("auth", 1).
+		AddStage("query", 4).
+		AddStage("render", 1)
+
+	for _, stage := range []string{"auth", "query", "render"} {
+		stageTimeout, _ := budget.StageTimeout(reqCtx, stage)
+		stageService := NewUserService(dbClient, stageTimeout)
+		stageService.GetUser(reqCtx, stage)
+	}
+
+	fmt.Println(string(make([]byte, 50)))
+
+	fmt.Println("\nExample 5: Pipeline with total budget")
+	pipeline := NewMultiStageTimeout(5*time.Second).
+		AddStage("load user", 4*time.Second, func(ctx context.Context) error {
+			_, err := dbClient.Query(ctx, "SELECT * FROM users WHERE id = 42")
+			return err
+		}).
+		AddOptionalStage("load history", 2*time.Second, func(ctx context.Context) error {
+			_, err := dbClient.Query(ctx, "SELECT * FROM history WHERE user_id = 42")
+			return err
+		}).
+		AddStage("render", 500*time.Millisecond, func(ctx context.Context) error {
+			return nil
+		})
+
+	results, err := pipeline.Run(ctx)
+	for _, res := range results {
+		switch {
+		case res.Skipped:
+			fmt.Printf("  %s: skipped (budget exhausted)\n", res.Name)
+		case res.Err != nil:
+			fmt.Printf("  %s: failed after %v: %v\n", res.Name, res.Duration, res.Err)
+		default:
+			fmt.Printf("  %s: ok in %v\n", res.Name, res.Duration)
+		}
+	}
+	if err != nil {
+		fmt.Printf("  Pipeline failed: %v\n", err)
+	}
+
+	fmt.Println(string(make([]byte, 50)))
+
+	fmt.Println("\nExample 6: HTTP handler over budget returns 504")
+	demonstrateHTTPTimeouts(dbClient)
+
+	time.Sleep(100 * time.Millisecond)
+	fmt.Printf("\nAbandoned goroutines: %d\n", AbandonedGoroutines())
+
+	fmt.Println()
+	registry.WriteText(os.Stdout)
+}
+
+func demonstrateHTTPTimeouts(dbClient *DatabaseClient) {
+	config := DefaultTimeoutConfig()
+
+	mux := http.NewServeMux()
+	mux.Handle("/users", TimeoutMiddleware(1*time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, err := dbClient.Query(r.Context(), "SELECT * FROM users")
+		if err != nil {
+			return
+		}
+		w.Write([]byte(result))
+	})))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Printf("Failed to listen: %v", err)
+		return
+	}
+
+	server := NewHTTPServer(listener.Addr().String(), mux, config)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := NewHTTPClient(config)
+	resp, err := client.Get("http://" + listener.Addr().String() + "/users")
+	if err != nil {
+		log.Printf("Request failed: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Printf("  Status: %d, body: %s", resp.StatusCode, body)
 }
@@ -0,0 +1,12 @@
+package main
+
+import "stability/metrics"
+
+func timeoutMetrics(registry *metrics.Registry) {
+	registry.NewCounterFunc("timeout_calls_total",
+		"Calls cut off by their deadline.", func() float64 { return float64(CallTimeouts()) })
+	registry.NewCounterFunc("timeout_handler_responses_total",
+		"504 responses sent by TimeoutMiddleware.", func() float64 { return float64(HandlerTimeouts()) })
+	registry.NewGaugeFunc("timeout_abandoned_goroutines",
+		"Timed-out calls still running because they ignored their context.", func() float64 { return float64(AbandonedGoroutines()) })
+}
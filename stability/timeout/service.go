@@ -2,25 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
+
+	"stability/faultinjection"
 )
 
 type DatabaseClient struct {
-	averageDelay time.Duration
+	faults *faultinjection.Injector
 }
 
-func NewDatabaseClient(avgDelay time.Duration) *DatabaseClient {
+func NewDatabaseClient(faults *faultinjection.Injector) *DatabaseClient {
 	return &DatabaseClient{
-		averageDelay: avgDelay,
+		faults: faults,
 	}
 }
 
-func (c *DatabaseClient) Query(query string) (string, error) {
-	delay := c.averageDelay + time.Duration(time.Now().UnixNano()%int64(c.averageDelay))
-	log.Printf("    Query will take %v...", delay)
-	time.Sleep(delay)
+func (c *DatabaseClient) Query(ctx context.Context, query string) (string, error) {
+	start := time.Now()
 
+	if err := c.faults.Inject(ctx); err != nil {
+		if ctx.Err() != nil {
+			log.Printf("    Query cancelled after %v: %v", time.Since(start), ctx.Err())
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("query failed: %w", err)
+	}
+
+	log.Printf("    Query took %v", time.Since(start))
 	return "result for: " + query, nil
 }
 
@@ -41,34 +52,30 @@ func (s *UserService) GetUser(ctx context.Context, userID string) (string, error
 
 	start := time.Now()
 
-	timeoutCtx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	timeoutCtx, cancel := context.WithTimeoutCause(ctx, s.queryTimeout, BudgetExpired("user query", s.queryTimeout))
 	defer cancel()
 
-	resultChan := make(chan string, 1)
-	errChan := make(chan error, 1)
+	result, err := ExecuteWithContextAndResult(timeoutCtx, func(ctx context.Context) (string, error) {
+		return s.db.Query(ctx, "SELECT * FROM users WHERE id = "+userID)
+	})
 
-	go func() {
-		result, err := s.db.Query("SELECT * FROM users WHERE id = " + userID)
-		if err != nil {
-			errChan <- err
-			return
-		}
-		resultChan <- result
-	}()
+	elapsed := time.Since(start)
 
-	select {
-	case result := <-resultChan:
-		elapsed := time.Since(start)
-		log.Printf("  Success in %v: %s\n", elapsed, result)
-		return result, nil
+	if errors.Is(err, ErrTimeout) {
+		log.Printf("  Timeout after %v: %v\n", elapsed, err)
+		return "", err
+	}
 
-	case err := <-errChan:
-		log.Printf("  Error: %v\n", err)
+	if errors.Is(err, context.Canceled) {
+		log.Printf("  Caller went away after %v: %v\n", elapsed, err)
 		return "", err
+	}
 
-	case <-timeoutCtx.Done():
-		elapsed := time.Since(start)
-		log.Printf("  Timeout after %v\n", elapsed)
-		return "", ErrTimeout
+	if err != nil {
+		log.Printf("  Error: %v\n", err)
+		return "", err
 	}
+
+	log.Printf("  Success in %v: %s\n", elapsed, result)
+	return result, nil
 }
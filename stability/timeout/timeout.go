@@ -3,60 +3,100 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var ErrTimeout = errors.New("operation timeout")
 
-func ExecuteWithTimeout(timeout time.Duration, fn func() error) error {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	return ExecuteWithContext(ctx, func(ctx context.Context) error {
-		return fn()
-	})
+// ContextError is returned when ctx ends before fn does. It wraps ErrTimeout
+// only when a deadline fired, so a caller that went away (context.Canceled)
+// is not mistaken for a slow dependency.
+type ContextError struct {
+	Err   error
+	Cause error
 }
 
-func ExecuteWithContext(ctx context.Context, fn func(context.Context) error) error {
-	errChan := make(chan error, 1)
+func (e *ContextError) Error() string {
+	msg := ErrTimeout.Error()
+	if errors.Is(e.Err, context.Canceled) {
+		msg = "operation canceled"
+	}
 
-	go func() {
-		errChan <- fn(ctx)
-	}()
+	if e.Cause != nil && e.Cause != e.Err {
+		return msg + ": " + e.Cause.Error()
+	}
+	return msg
+}
 
-	select {
-	case err := <-errChan:
-		return err
-	case <-ctx.Done():
-		return ErrTimeout
+func (e *ContextError) Unwrap() []error {
+	errs := []error{e.Err}
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		errs = append(errs, ErrTimeout)
 	}
+	if e.Cause != nil && e.Cause != e.Err {
+		errs = append(errs, e.Cause)
+	}
+	return errs
 }
 
-func ExecuteWithTimeoutAndResult[T any](timeout time.Duration, fn func() (T, error)) (T, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+func contextError(ctx context.Context) error {
+	return &ContextError{
+		Err:   ctx.Err(),
+		Cause: context.Cause(ctx),
+	}
+}
+
+// BudgetExpired is the deadline cause that names the layer whose budget ran out.
+func BudgetExpired(layer string, timeout time.Duration) error {
+	return fmt.Errorf("%s budget of %v expired", layer, timeout)
+}
+
+var (
+	abandonedGoroutines atomic.Int64
+	callTimeouts        atomic.Int64
+)
+
+// CallTimeouts reports how many calls were cut off by their deadline.
+func CallTimeouts() int64 {
+	return callTimeouts.Load()
+}
+
+// AbandonedGoroutines reports how many timed-out calls are still running
+// because fn ignored its context.
+func AbandonedGoroutines() int64 {
+	return abandonedGoroutines.Load()
+}
+
+func ExecuteWithTimeout(timeout time.Duration, fn func(context.Context) error) error {
+	ctx, cancel := context.WithTimeoutCause(context.Background(), timeout, BudgetExpired("operation", timeout))
 	defer cancel()
 
-	type result struct {
-		value T
-		err   error
-	}
+	return ExecuteWithContext(ctx, fn)
+}
 
-	resultChan := make(chan result, 1)
+func ExecuteWithContext(ctx context.Context, fn func(context.Context) error) error {
+	_, err := ExecuteWithContextAndResult(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
 
-	go func() {
-		val, err := fn()
-		resultChan <- result{value: val, err: err}
-	}()
+func ExecuteWithTimeoutAndResult[T any](timeout time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeoutCause(context.Background(), timeout, BudgetExpired("operation", timeout))
+	defer cancel()
 
-	select {
-	case res := <-resultChan:
-		return res.value, res.err
-	case <-ctx.Done():
-		var zero T
-		return zero, ErrTimeout
-	}
+	return ExecuteWithContextAndResult(ctx, fn)
 }
 
+const (
+	callRunning int32 = iota
+	callFinished
+	callAbandoned
+)
+
 func ExecuteWithContextAndResult[T any](ctx context.Context, fn func(context.Context) (T, error)) (T, error) {
 	type result struct {
 		value T
@@ -65,17 +105,29 @@ func ExecuteWithContextAndResult[T any](ctx context.Context, fn func(context.Con
 
 	resultChan := make(chan result, 1)
 
+	var state atomic.Int32
+
 	go func() {
 		val, err := fn(ctx)
 		resultChan <- result{value: val, err: err}
+
+		if !state.CompareAndSwap(callRunning, callFinished) {
+			abandonedGoroutines.Add(-1)
+		}
 	}()
 
 	select {
 	case res := <-resultChan:
 		return res.value, res.err
 	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			callTimeouts.Add(1)
+		}
+		if state.CompareAndSwap(callRunning, callAbandoned) {
+			abandonedGoroutines.Add(1)
+		}
 		var zero T
-		return zero, ErrTimeout
+		return zero, contextError(ctx)
 	}
 }
 
@@ -87,46 +139,107 @@ func NewTimeoutWrapper(timeout time.Duration) *TimeoutWrapper {
 	return &TimeoutWrapper{timeout: timeout}
 }
 
-func (tw *TimeoutWrapper) Execute(fn func() error) error {
+func (tw *TimeoutWrapper) Execute(fn func(context.Context) error) error {
 	return ExecuteWithTimeout(tw.timeout, fn)
 }
 
 func (tw *TimeoutWrapper) ExecuteWithContext(ctx context.Context, fn func(context.Context) error) error {
-
-	timeoutCtx, cancel := context.WithTimeout(ctx, tw.timeout)
+	timeoutCtx, cancel := context.WithTimeoutCause(ctx, tw.timeout, BudgetExpired("timeout wrapper", tw.timeout))
 	defer cancel()
 
 	return ExecuteWithContext(timeoutCtx, fn)
 }
 
-type TimeoutConfig struct {
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+type Stage struct {
+	Name     string
+	Timeout  time.Duration
+	Optional bool
+	Fn       func(context.Context) error
+}
+
+type StageResult struct {
+	Name     string
+	Duration time.Duration
+	Skipped  bool
+	Err      error
+}
+
+type PipelineError struct {
+	Stage   string
+	Err     error
+	Results []StageResult
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("stage %s failed: %v", e.Stage, e.Err)
 }
 
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// MultiStageTimeout runs stages in registration order under a shared total
+// budget. Optional stages are skipped when the budget left is smaller than
+// their own timeout, and their failures don't stop the pipeline.
 type MultiStageTimeout struct {
-	stages map[string]time.Duration
+	total  time.Duration
+	stages []Stage
 }
 
-func NewMultiStageTimeout() *MultiStageTimeout {
-	return &MultiStageTimeout{
-		stages: make(map[string]time.Duration),
-	}
+func NewMultiStageTimeout(total time.Duration) *MultiStageTimeout {
+	return &MultiStageTimeout{total: total}
+}
+
+func (mt *MultiStageTimeout) AddStage(name string, timeout time.Duration, fn func(context.Context) error) *MultiStageTimeout {
+	mt.stages = append(mt.stages, Stage{Name: name, Timeout: timeout, Fn: fn})
+	return mt
 }
 
-func (mt *MultiStageTimeout) AddStage(name string, timeout time.Duration) *MultiStageTimeout {
-	mt.stages[name] = timeout
+func (mt *MultiStageTimeout) AddOptionalStage(name string, timeout time.Duration, fn func(context.Context) error) *MultiStageTimeout {
+	mt.stages = append(mt.stages, Stage{Name: name, Timeout: timeout, Optional: true, Fn: fn})
 	return mt
 }
 
-func (mt *MultiStageTimeout) ExecuteStage(stageName string, fn func() error) error {
-	timeout, exists := mt.stages[stageName]
-	if !exists {
-		return errors.New("unknown stage: " + stageName)
+func (mt *MultiStageTimeout) Run(ctx context.Context) ([]StageResult, error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, mt.total, BudgetExpired("pipeline", mt.total))
+	defer cancel()
+
+	results := make([]StageResult, 0, len(mt.stages))
+
+	for _, stage := range mt.stages {
+		if stage.Optional && !hasBudget(ctx, stage.Timeout) {
+			results = append(results, StageResult{Name: stage.Name, Skipped: true})
+			continue
+		}
+
+		if ctx.Err() != nil {
+			err := contextError(ctx)
+			results = append(results, StageResult{Name: stage.Name, Err: err})
+			return results, &PipelineError{Stage: stage.Name, Err: err, Results: results}
+		}
+
+		start := time.Now()
+		stageCtx, stageCancel := context.WithTimeoutCause(ctx, stage.Timeout, BudgetExpired("stage "+stage.Name, stage.Timeout))
+		err := ExecuteWithContext(stageCtx, stage.Fn)
+		stageCancel()
+
+		results = append(results, StageResult{Name: stage.Name, Duration: time.Since(start), Err: err})
+
+		if err != nil && !stage.Optional {
+			return results, &PipelineError{Stage: stage.Name, Err: err, Results: results}
+		}
 	}
 
-	return ExecuteWithTimeout(timeout, fn)
+	return results, nil
+}
+
+func hasBudget(ctx context.Context, need time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) >= need
 }
 
 type AdaptiveTimeout struct {
@@ -136,6 +249,14 @@ type AdaptiveTimeout struct {
 	successCount   int
 	failureCount   int
 	adjustFactor   float64
+
+	percentile float64
+	multiplier float64
+	warmup     int
+	samples    int
+	latencies  *latencyHistogram
+
+	mu sync.Mutex
 }
 
 func NewAdaptiveTimeout(min, max, initial time.Duration) *AdaptiveTimeout {
@@ -147,36 +268,91 @@ func NewAdaptiveTimeout(min, max, initial time.Duration) *AdaptiveTimeout {
 	}
 }
 
-func (at *AdaptiveTimeout) Execute(fn func() error) error {
+// NewPercentileAdaptiveTimeout keeps the timeout at multiplier × the given
+// percentile of recent latencies, e.g. p99 × 1.5. Until warmup calls have
+// been observed the initial timeout is used.
+func NewPercentileAdaptiveTimeout(min, max, initial time.Duration, percentile, multiplier float64, warmup int) *AdaptiveTimeout {
+	return &AdaptiveTimeout{
+		minTimeout:     min,
+		maxTimeout:     max,
+		currentTimeout: initial,
+		percentile:     percentile,
+		multiplier:     multiplier,
+		warmup:         warmup,
+		latencies:      newLatencyHistogram(1000),
+	}
+}
+
+func (at *AdaptiveTimeout) Execute(fn func(context.Context) error) error {
+	timeout := at.GetCurrentTimeout()
+
 	start := time.Now()
-	err := ExecuteWithTimeout(at.currentTimeout, fn)
+	err := ExecuteWithTimeout(timeout, fn)
 	elapsed := time.Since(start)
 
-	if err == ErrTimeout {
-		at.failureCount++
+	at.mu.Lock()
+	defer at.mu.Unlock()
 
-		at.currentTimeout = time.Duration(float64(at.currentTimeout) * at.adjustFactor)
-		if at.currentTimeout > at.maxTimeout {
-			at.currentTimeout = at.maxTimeout
-		}
+	if errors.Is(err, ErrTimeout) {
+		at.failureCount++
 	} else if err == nil {
 		at.successCount++
+	}
 
-		if elapsed < at.currentTimeout/2 {
-			at.currentTimeout = time.Duration(float64(at.currentTimeout) / at.adjustFactor)
-			if at.currentTimeout < at.minTimeout {
-				at.currentTimeout = at.minTimeout
-			}
-		}
+	if at.latencies != nil {
+		at.adjustByPercentile(err, elapsed)
+	} else {
+		at.adjustByFactor(err, elapsed)
 	}
 
 	return err
 }
 
+func (at *AdaptiveTimeout) adjustByFactor(err error, elapsed time.Duration) {
+	if errors.Is(err, ErrTimeout) {
+		at.currentTimeout = at.clamp(time.Duration(float64(at.currentTimeout) * at.adjustFactor))
+	} else if err == nil && elapsed < at.currentTimeout/2 {
+		at.currentTimeout = at.clamp(time.Duration(float64(at.currentTimeout) / at.adjustFactor))
+	}
+}
+
+func (at *AdaptiveTimeout) adjustByPercentile(err error, elapsed time.Duration) {
+	// A timed-out call still tells us the latency was at least elapsed.
+	if err != nil && !errors.Is(err, ErrTimeout) {
+		return
+	}
+
+	at.latencies.Add(elapsed)
+	at.samples++
+
+	if at.samples < at.warmup {
+		return
+	}
+
+	target := float64(at.latencies.Quantile(at.percentile)) * at.multiplier
+	at.currentTimeout = at.clamp(time.Duration(target))
+}
+
+func (at *AdaptiveTimeout) clamp(timeout time.Duration) time.Duration {
+	if timeout > at.maxTimeout {
+		return at.maxTimeout
+	}
+	if timeout < at.minTimeout {
+		return at.minTimeout
+	}
+	return timeout
+}
+
 func (at *AdaptiveTimeout) GetCurrentTimeout() time.Duration {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
 	return at.currentTimeout
 }
 
 func (at *AdaptiveTimeout) GetStats() (success, failures int, current time.Duration) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
 	return at.successCount, at.failureCount, at.currentTimeout
 }
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+type TimeoutConfig struct {
+	QueryTimeout     time.Duration
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+}
+
+// DB wraps *sql.DB so every query runs under QueryTimeout on the client side
+// and under statement_timeout / lock_timeout on the Postgres side.
+type DB struct {
+	db           *sql.DB
+	config       TimeoutConfig
+	queryTimeout time.Duration
+	overridden   bool
+}
+
+// Open adds the session settings to the lib/pq connection string, either as
+// key=value pairs or as query parameters of a postgres:// URL; pq sends
+// unknown keys to the server as run-time parameters for every pooled connection.
+func Open(connStr string, config TimeoutConfig) (*DB, error) {
+	settings := map[string]time.Duration{
+		"statement_timeout": config.StatementTimeout,
+		"lock_timeout":      config.LockTimeout,
+	}
+
+	connStr, err := withSettings(connStr, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(db, config), nil
+}
+
+func withSettings(connStr string, settings map[string]time.Duration) (string, error) {
+	if !strings.HasPrefix(connStr, "postgres://") && !strings.HasPrefix(connStr, "postgresql://") {
+		for _, key := range []string{"statement_timeout", "lock_timeout"} {
+			if settings[key] > 0 {
+				connStr += fmt.Sprintf(" %s=%d", key, settings[key].Milliseconds())
+			}
+		}
+		return connStr, nil
+	}
+
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid connection URL: %w", err)
+	}
+
+	query := u.Query()
+	for key, value := range settings {
+		if value > 0 {
+			query.Set(key, strconv.FormatInt(value.Milliseconds(), 10))
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func New(db *sql.DB, config TimeoutConfig) *DB {
+	return &DB{
+		db:           db,
+		config:       config,
+		queryTimeout: config.QueryTimeout,
+	}
+}
+
+// WithTimeout returns a copy of db that uses timeout for its statements.
+func (db *DB) WithTimeout(timeout time.Duration) *DB {
+	override := *db
+	override.queryTimeout = timeout
+	override.overridden = true
+	return &override
+}
+
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.queryTimeout)
+}
+
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+func (r *Rows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+type Row struct {
+	row    *sql.Row
+	cancel context.CancelFunc
+}
+
+func (r *Row) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
+
+func (r *Row) Err() error {
+	return r.row.Err()
+}
+
+func (db *DB) Query(query string, args ...any) (*Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	ctx, cancel := db.withTimeout(ctx)
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &Rows{Rows: rows, cancel: cancel}, nil
+}
+
+func (db *DB) QueryRow(query string, args ...any) *Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	ctx, cancel := db.withTimeout(ctx)
+	return &Row{row: db.db.QueryRowContext(ctx, query, args...), cancel: cancel}
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.db.ExecContext(ctx, query, args...)
+}
+
+func (db *DB) Begin() (*sql.Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx scopes statement_timeout and lock_timeout to the transaction with
+// SET LOCAL, since statements on *sql.Tx carry no per-query context.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := db.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.setLocalTimeouts(ctx, tx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func (db *DB) setLocalTimeouts(ctx context.Context, tx *sql.Tx) error {
+	statementTimeout := db.config.StatementTimeout
+	if db.overridden {
+		statementTimeout = db.queryTimeout
+	}
+
+	if statementTimeout > 0 {
+		query := fmt.Sprintf("SET LOCAL statement_timeout = %d", statementTimeout.Milliseconds())
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to set statement_timeout: %w", err)
+		}
+	}
+
+	if db.config.LockTimeout > 0 {
+		query := fmt.Sprintf("SET LOCAL lock_timeout = %d", db.config.LockTimeout.Milliseconds())
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to set lock_timeout: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) Ping() error {
+	ctx, cancel := db.withTimeout(context.Background())
+	defer cancel()
+
+	return db.db.PingContext(ctx)
+}
+
+func (db *DB) Close() error {
+	return db.db.Close()
+}
@@ -2,19 +2,17 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	"transactional_outbox/database"
 	"transactional_outbox/models"
 	"transactional_outbox/publisher"
 	"transactional_outbox/repository"
 	"transactional_outbox/service"
-
-	_ "github.com/lib/pq"
 )
 
 const (
@@ -29,6 +27,10 @@ const (
 
 	pollInterval = 2 * time.Second
 	batchSize    = 10
+
+	queryTimeout     = 5 * time.Second
+	statementTimeout = 5 * time.Second
+	lockTimeout      = 2 * time.Second
 )
 
 func main() {
@@ -89,14 +91,18 @@ func main() {
 	log.Println("Goodbye!")
 }
 
-func connectToDatabase() (*sql.DB, error) {
+func connectToDatabase() (*database.DB, error) {
 	connStr := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		dbHost, dbPort, dbUser, dbPassword, dbName,
 	)
 
 	log.Println("Connecting to PostgreSQL...")
-	db, err := sql.Open("postgres", connStr)
+	db, err := database.Open(connStr, database.TimeoutConfig{
+		QueryTimeout:     queryTimeout,
+		StatementTimeout: statementTimeout,
+		LockTimeout:      lockTimeout,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +179,7 @@ func demonstrateTransactionalOutbox(orderService *service.OrderService, orderRep
 	}
 }
 
-func showStatistics(db *sql.DB, orderRepo repository.OrderRepository, outboxRepo repository.OutboxRepository) {
+func showStatistics(db *database.DB, orderRepo repository.OrderRepository, outboxRepo repository.OutboxRepository) {
 	log.Println()
 
 	orders, err := orderRepo.List(100, 0)
@@ -2,17 +2,17 @@ package publisher
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
 	"time"
 
+	"transactional_outbox/database"
 	"transactional_outbox/models"
 	"transactional_outbox/repository"
 )
 
 type OutboxProcessor struct {
-	db             *sql.DB
+	db             *database.DB
 	outboxRepo     repository.OutboxRepository
 	kafkaPublisher *KafkaPublisher
 	pollInterval   time.Duration
@@ -20,7 +20,7 @@ type OutboxProcessor struct {
 }
 
 func NewOutboxProcessor(
-	db *sql.DB,
+	db *database.DB,
 	outboxRepo repository.OutboxRepository,
 	kafkaPublisher *KafkaPublisher,
 	pollInterval time.Duration,
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"transactional_outbox/database"
 	"transactional_outbox/models"
 )
 
@@ -15,10 +16,10 @@ type OrderRepository interface {
 }
 
 type orderRepository struct {
-	db *sql.DB
+	db *database.DB
 }
 
-func NewOrderRepository(db *sql.DB) OrderRepository {
+func NewOrderRepository(db *database.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"transactional_outbox/database"
 	"transactional_outbox/models"
 )
 
@@ -16,10 +17,10 @@ type OutboxRepository interface {
 }
 
 type outboxRepository struct {
-	db *sql.DB
+	db *database.DB
 }
 
-func NewOutboxRepository(db *sql.DB) OutboxRepository {
+func NewOutboxRepository(db *database.DB) OutboxRepository {
 	return &outboxRepository{db: db}
 }
 
@@ -1,23 +1,23 @@
 package service
 
 import (
-	"database/sql"
 	"fmt"
 	"log"
 	"strconv"
 
+	"transactional_outbox/database"
 	"transactional_outbox/models"
 	"transactional_outbox/repository"
 )
 
 type OrderService struct {
-	db         *sql.DB
+	db         *database.DB
 	orderRepo  repository.OrderRepository
 	outboxRepo repository.OutboxRepository
 }
 
 func NewOrderService(
-	db *sql.DB,
+	db *database.DB,
 	orderRepo repository.OrderRepository,
 	outboxRepo repository.OutboxRepository,
 ) *OrderService {